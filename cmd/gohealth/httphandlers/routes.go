@@ -16,14 +16,16 @@ type Middleware struct {
 }
 
 // Routes return application routes handlers
-func Routes(logger *log.Logger, str *sitestore.Store, broker *sse.Broker, sse bool) http.Handler {
+func Routes(logger *log.Logger, str sitestore.Store, broker *sse.Broker, sse bool) http.Handler {
 	router := http.DefaultServeMux
 
 	shh := SiteHealthHandler{SiteStore: str, SSE: sse}
 	router.HandleFunc("/", shh.Homepage)
 	router.HandleFunc("/sites/save", shh.Save)
+	router.HandleFunc("/sites/", shh.Show)
 	router.HandleFunc("/ajax/sites/check", shh.HealthChecks)
 	router.HandleFunc("/ajax/sites/delete/", shh.Delete)
+	router.HandleFunc("/api/sites/", shh.History)
 
 	if sse {
 		router.HandleFunc("/sse", broker.SSE)
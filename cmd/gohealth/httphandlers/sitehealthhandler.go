@@ -2,10 +2,12 @@ package httphandlers
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/levady/gohealth/internal/platform/sitestore"
 )
@@ -29,11 +31,14 @@ type Payload struct {
 
 // SiteHealthHandler represents SiteHealthHandler data
 type SiteHealthHandler struct {
-	SiteStore *sitestore.Store
+	SiteStore sitestore.Store
 	SSE       bool
 }
 
-var homepageTplPath = "web/templates/homepage.html"
+var (
+	homepageTplPath = "web/templates/homepage.html"
+	siteTplPath     = "web/templates/site.html"
+)
 
 // Homepage renders the home page
 func (handler *SiteHealthHandler) Homepage(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +55,29 @@ func (handler *SiteHealthHandler) Homepage(w http.ResponseWriter, r *http.Reques
 	renderHomepage(w, p, http.StatusOK)
 }
 
+// Show renders the detail page of a site
+func (handler *SiteHealthHandler) Show(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	siteIDStr := r.URL.Path[len("/sites/"):]
+	siteID, err := strconv.Atoi(siteIDStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	site, err := handler.SiteStore.Get(siteID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	renderSite(w, site, http.StatusOK)
+}
+
 // Save saves a site to the store
 func (handler *SiteHealthHandler) Save(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -58,9 +86,75 @@ func (handler *SiteHealthHandler) Save(w http.ResponseWriter, r *http.Request) {
 	}
 
 	url := r.FormValue("url")
-	s := sitestore.Site{URL: strings.TrimSpace(url)}
+	s := sitestore.Site{
+		URL:            strings.TrimSpace(url),
+		CheckType:      r.FormValue("check_type"),
+		ExpectedStatus: strings.TrimSpace(r.FormValue("expected_status")),
+	}
+
+	if send, expect := r.FormValue("tcp_send"), r.FormValue("tcp_expect"); send != "" || expect != "" {
+		s.TCP = &sitestore.TCPOptions{Send: unescaper.Replace(send), Expect: expect}
+	}
+
+	if s.CheckType == sitestore.DNSCheck {
+		s.DNS = &sitestore.DNSOptions{
+			RecordType: r.FormValue("dns_record_type"),
+			Resolver:   strings.TrimSpace(r.FormValue("dns_resolver")),
+		}
+		for _, v := range strings.Split(r.FormValue("dns_expect"), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				s.DNS.Expect = append(s.DNS.Expect, v)
+			}
+		}
+	}
 
-	if err := handler.SiteStore.Add(s); err != nil {
+	if send, expect := r.FormValue("websocket_send"), r.FormValue("websocket_expect"); send != "" || expect != "" {
+		s.WebSocket = &sitestore.WebSocketOptions{Send: send, Expect: expect}
+	}
+
+	if v := strings.TrimSpace(r.FormValue("dsn")); v != "" {
+		s.DataStore = &sitestore.DataStoreOptions{DSN: v}
+	}
+
+	if r.FormValue("watch_content") != "" {
+		s.Content = &sitestore.ContentOptions{Extract: strings.TrimSpace(r.FormValue("content_extract"))}
+		for _, line := range strings.Split(r.FormValue("content_strip"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				s.Content.Strip = append(s.Content.Strip, line)
+			}
+		}
+	}
+
+	if r.FormValue("starttls") != "" {
+		s.Mail = &sitestore.MailOptions{StartTLS: true}
+	}
+
+	if v := strings.TrimSpace(r.FormValue("grpc_service")); v != "" {
+		s.GRPC = &sitestore.GRPCOptions{Service: v}
+	}
+
+	if v := r.FormValue("assertion_value"); strings.TrimSpace(v) != "" {
+		s.BodyAssertions = []sitestore.BodyAssertion{{Type: r.FormValue("assertion_type"), Value: v}}
+	}
+
+	err := parseInterval(r.FormValue("interval"), &s)
+	if err == nil {
+		err = parseThresholds(r, &s)
+	}
+	if err == nil {
+		err = parseRetry(r, &s)
+	}
+	if err == nil {
+		err = parseRequest(r, &s)
+	}
+	if err == nil {
+		err = parseTLS(r, &s)
+	}
+	if err == nil {
+		err = handler.SiteStore.Add(s)
+	}
+
+	if err != nil {
 		errData := ErrorData{Msg: err.Error()}
 		data := Data{
 			Sites: handler.SiteStore.List(),
@@ -113,7 +207,64 @@ func (handler *SiteHealthHandler) HealthChecks(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	json, err := json.Marshal(handler.SiteStore.List())
+	sites := handler.SiteStore.List()
+	resp := make([]siteJSON, len(sites))
+	for i, s := range sites {
+		resp[i] = siteJSON{Site: s, StatusText: s.StatusText()}
+	}
+
+	json, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+}
+
+// siteJSON represents a site in the JSON API, with its status spelled out
+type siteJSON struct {
+	sitestore.Site
+	StatusText string `json:"status_text"`
+}
+
+// History returns the check history of a site, optionally bounded by the
+// `from` and `to` RFC3339 query parameters
+func (handler *SiteHealthHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" || !strings.HasSuffix(r.URL.Path, "/history") {
+		http.NotFound(w, r)
+		return
+	}
+
+	siteIDStr := strings.TrimSuffix(r.URL.Path[len("/api/sites/"):], "/history")
+	siteID, err := strconv.Atoi(siteIDStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var from, to time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "400 bad request. `from` must be an RFC3339 time.", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "400 bad request. `to` must be an RFC3339 time.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := handler.SiteStore.History(siteID, from, to)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	json, err := json.Marshal(results)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -123,8 +274,150 @@ func (handler *SiteHealthHandler) HealthChecks(w http.ResponseWriter, r *http.Re
 	w.Write(json)
 }
 
+// unescaper turns the escape sequences typed in forms into control characters
+var unescaper = strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t")
+
+// parseInterval sets the site interval from its form value, in seconds
+func parseInterval(v string, s *sitestore.Site) error {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+
+	interval, err := strconv.Atoi(v)
+	if err != nil {
+		return errors.New("Site interval must be a number of seconds")
+	}
+
+	s.Interval = interval
+	return nil
+}
+
+// parseThresholds sets the site latency thresholds, in milliseconds, and its
+// failure and recovery thresholds, in consecutive checks, from their form values
+func parseThresholds(r *http.Request, s *sitestore.Site) error {
+	thresholds := []struct {
+		field string
+		dst   *int
+		msg   string
+	}{
+		{field: "warning_latency", dst: &s.WarningLatency, msg: "Site latency thresholds must be a number of milliseconds"},
+		{field: "critical_latency", dst: &s.CriticalLatency, msg: "Site latency thresholds must be a number of milliseconds"},
+		{field: "fail_threshold", dst: &s.FailThreshold, msg: "Site failure threshold must be a number of checks"},
+		{field: "recover_threshold", dst: &s.RecoverThreshold, msg: "Site recovery threshold must be a number of checks"},
+	}
+
+	for _, th := range thresholds {
+		v := strings.TrimSpace(r.FormValue(th.field))
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New(th.msg)
+		}
+		*th.dst = n
+	}
+
+	return nil
+}
+
+// parseRetry sets the site retry policy from its form values. The backoff is
+// given in milliseconds, and the retried error classes as `retry_on` values.
+func parseRetry(r *http.Request, s *sitestore.Site) error {
+	v := strings.TrimSpace(r.FormValue("retry_attempts"))
+	if v == "" {
+		return nil
+	}
+
+	attempts, err := strconv.Atoi(v)
+	if err != nil {
+		return errors.New("Site retry attempts must be a number")
+	}
+	s.Retry = &sitestore.RetryOptions{Attempts: attempts, ErrorClasses: r.Form["retry_on"]}
+
+	if v := strings.TrimSpace(r.FormValue("retry_backoff")); v != "" {
+		backoff, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("Site retry backoff must be a number of milliseconds")
+		}
+		s.Retry.Backoff = backoff
+	}
+
+	return nil
+}
+
+// parseTLS sets the site TLS options from its form values. TLS checks always
+// inspect the certificate, HTTPS checks only when asked to.
+func parseTLS(r *http.Request, s *sitestore.Site) error {
+	if s.CheckType != sitestore.TLSCheck && r.FormValue("inspect_certificate") == "" {
+		return nil
+	}
+
+	s.TLS = &sitestore.TLSOptions{}
+
+	v := strings.TrimSpace(r.FormValue("expiry_warning_days"))
+	if v == "" {
+		return nil
+	}
+
+	days, err := strconv.Atoi(v)
+	if err != nil {
+		return errors.New("Site expiry warning days must be a number")
+	}
+
+	s.TLS.ExpiryWarningDays = days
+	return nil
+}
+
+// parseRequest sets the site HTTP request from its form values. Headers are
+// given one `Name: value` per line.
+func parseRequest(r *http.Request, s *sitestore.Site) error {
+	req := sitestore.HTTPRequest{
+		Method:            strings.ToUpper(strings.TrimSpace(r.FormValue("method"))),
+		Body:              r.FormValue("request_body"),
+		BearerToken:       strings.TrimSpace(r.FormValue("bearer_token")),
+		BasicAuthUser:     strings.TrimSpace(r.FormValue("basic_auth_user")),
+		BasicAuthPassword: strings.TrimSpace(r.FormValue("basic_auth_password")),
+	}
+
+	for _, line := range strings.Split(r.FormValue("headers"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return errors.New("Request headers must be given as `Name: value` lines")
+		}
+
+		if req.Headers == nil {
+			req.Headers = make(map[string]string)
+		}
+		req.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	if req.Method == "GET" {
+		req.Method = ""
+	}
+
+	if req.Method != "" || req.Body != "" || len(req.Headers) > 0 || req.BearerToken != "" || req.BasicAuthUser != "" {
+		s.Request = &req
+	}
+
+	return nil
+}
+
 func renderHomepage(w http.ResponseWriter, p Payload, statusCode int) error {
 	t, _ := template.ParseFiles(homepageTplPath)
 	w.WriteHeader(statusCode)
 	return t.Execute(w, p)
 }
+
+func renderSite(w http.ResponseWriter, s sitestore.Site, statusCode int) error {
+	t, _ := template.ParseFiles(siteTplPath)
+	w.WriteHeader(statusCode)
+	return t.Execute(w, s)
+}
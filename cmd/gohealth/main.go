@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"expvar"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -14,20 +15,38 @@ import (
 	"time"
 
 	"github.com/levady/gohealth/cmd/gohealth/httphandlers"
+	"github.com/levady/gohealth/internal/platform/secrets"
 	"github.com/levady/gohealth/internal/platform/sitestore"
 	"github.com/levady/gohealth/internal/platform/sse"
 	"github.com/levady/gohealth/internal/sitehealthchecker"
 )
 
 type config struct {
-	Host           string
-	LookbackPeriod int
-	SSE            bool
+	Host             string
+	LookbackPeriod   int
+	SSE              bool
+	Storage          string
+	StoragePath      string
+	SnapshotPath     string
+	SnapshotInterval int
+	Stagger          bool
+	Jitter           int
+	SecretsFile      string
+	DrainTimeout     int
+	Workers          int
+	QueueSize        int
+	HostMaxInFlight  int
+	HostRate         float64
+	HostBurst        int
+	HostLimitsFile   string
 }
 
 // build is the git version of this program. It is set using build flags in the makefile.
 var build = "develop"
 
+// checkTimeout is how long a single site check may take
+const checkTimeout = 800 * time.Millisecond
+
 func main() {
 	if err := run(); err != nil {
 		log.Println("error :", err)
@@ -66,10 +85,124 @@ func run() error {
 		}
 	}
 
+	storage := os.Getenv("STORAGE")
+	if storage == "" {
+		storage = "memory"
+	}
+	if storage != "memory" && storage != "bolt" {
+		return errors.New("main : STORAGE config must be either memory or bolt")
+	}
+
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "gohealth.db"
+	}
+
+	snapshotPath := os.Getenv("SNAPSHOT_PATH")
+
+	siCfg := 60
+	if si := os.Getenv("SNAPSHOT_INTERVAL"); si != "" {
+		var err error
+		siCfg, err = strconv.Atoi(si)
+		if err != nil || siCfg <= 0 {
+			return errors.New("main : Failed parsing SNAPSHOT_INTERVAL config")
+		}
+	}
+
+	staggerCfg := false
+	if stagger := os.Getenv("STAGGER"); stagger != "" {
+		var err error
+		staggerCfg, err = strconv.ParseBool(stagger)
+		if err != nil {
+			return errors.New("main : Failed parsing STAGGER config")
+		}
+	}
+
+	jitterCfg := 0
+	if jitter := os.Getenv("JITTER"); jitter != "" {
+		var err error
+		jitterCfg, err = strconv.Atoi(jitter)
+		if err != nil || jitterCfg < 0 {
+			return errors.New("main : Failed parsing JITTER config")
+		}
+	}
+
+	secretsFile := os.Getenv("SECRETS_FILE")
+
+	dtCfg := 5
+	if dt := os.Getenv("DRAIN_TIMEOUT"); dt != "" {
+		var err error
+		dtCfg, err = strconv.Atoi(dt)
+		if err != nil || dtCfg < 0 {
+			return errors.New("main : Failed parsing DRAIN_TIMEOUT config")
+		}
+	}
+
+	workersCfg := sitehealthchecker.DefaultWorkers
+	if workers := os.Getenv("WORKERS"); workers != "" {
+		var err error
+		workersCfg, err = strconv.Atoi(workers)
+		if err != nil || workersCfg <= 0 {
+			return errors.New("main : Failed parsing WORKERS config")
+		}
+	}
+
+	qsCfg := sitehealthchecker.DefaultQueueSize
+	if qs := os.Getenv("QUEUE_SIZE"); qs != "" {
+		var err error
+		qsCfg, err = strconv.Atoi(qs)
+		if err != nil || qsCfg <= 0 {
+			return errors.New("main : Failed parsing QUEUE_SIZE config")
+		}
+	}
+
+	hmifCfg := 4
+	if hmif := os.Getenv("HOST_MAX_IN_FLIGHT"); hmif != "" {
+		var err error
+		hmifCfg, err = strconv.Atoi(hmif)
+		if err != nil || hmifCfg < 0 {
+			return errors.New("main : Failed parsing HOST_MAX_IN_FLIGHT config")
+		}
+	}
+
+	hrCfg := 0.0
+	if hr := os.Getenv("HOST_RATE"); hr != "" {
+		var err error
+		hrCfg, err = strconv.ParseFloat(hr, 64)
+		if err != nil || hrCfg < 0 {
+			return errors.New("main : Failed parsing HOST_RATE config")
+		}
+	}
+
+	hbCfg := 1
+	if hb := os.Getenv("HOST_BURST"); hb != "" {
+		var err error
+		hbCfg, err = strconv.Atoi(hb)
+		if err != nil || hbCfg < 0 {
+			return errors.New("main : Failed parsing HOST_BURST config")
+		}
+	}
+
+	hostLimitsFile := os.Getenv("HOST_LIMITS_FILE")
+
 	cfg := config{
-		Host:           host,
-		LookbackPeriod: lpCfg,
-		SSE:            sseCfg,
+		Host:             host,
+		LookbackPeriod:   lpCfg,
+		SSE:              sseCfg,
+		Storage:          storage,
+		StoragePath:      storagePath,
+		SnapshotPath:     snapshotPath,
+		SnapshotInterval: siCfg,
+		Stagger:          staggerCfg,
+		Jitter:           jitterCfg,
+		SecretsFile:      secretsFile,
+		DrainTimeout:     dtCfg,
+		Workers:          workersCfg,
+		QueueSize:        qsCfg,
+		HostMaxInFlight:  hmifCfg,
+		HostRate:         hrCfg,
+		HostBurst:        hbCfg,
+		HostLimitsFile:   hostLimitsFile,
 	}
 
 	prettyCfg, err := json.MarshalIndent(cfg, "", "  ")
@@ -79,10 +212,66 @@ func run() error {
 	log.Printf("main : Config :%v", string(prettyCfg))
 
 	// =========================================================================
-	// Initializaing site memory store
+	// Initializaing site store
+
+	var str sitestore.Store
+	var snapshotStore *sitestore.MemoryStore
+	switch cfg.Storage {
+	case "bolt":
+		log.Printf("main : Initializing site bolt store at %s", cfg.StoragePath)
+		bs, err := sitestore.NewBoltStore(cfg.StoragePath)
+		if err != nil {
+			return fmt.Errorf("main : Failed opening bolt store : %v", err)
+		}
+		defer bs.Close()
+		str = bs
+
+	default:
+		log.Printf("main : Initializing site memory store")
+		ms := sitestore.NewStore()
+		if cfg.SnapshotPath != "" {
+			log.Printf("main : Restoring site memory store from %s", cfg.SnapshotPath)
+			if err := ms.Restore(cfg.SnapshotPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("main : Failed restoring snapshot : %v", err)
+			}
+			snapshotStore = &ms
+		}
+		str = &ms
+	}
+
+	// =========================================================================
+	// Loading secrets
+
+	if cfg.SecretsFile != "" {
+		log.Printf("main : Loading secrets from %s", cfg.SecretsFile)
+		resolver, err := secrets.Load(cfg.SecretsFile)
+		if err != nil {
+			return fmt.Errorf("main : Failed loading secrets : %v", err)
+		}
+		sitehealthchecker.UseSecrets(resolver)
+	}
+
+	// =========================================================================
+	// Limiting checks per host
 
-	log.Printf("main : Initializing site memory store")
-	str := sitestore.NewStore()
+	var hostLimits map[string]sitehealthchecker.HostLimit
+	if cfg.HostLimitsFile != "" {
+		log.Printf("main : Loading host limits from %s", cfg.HostLimitsFile)
+		hostLimits, err = sitehealthchecker.LoadHostLimits(cfg.HostLimitsFile)
+		if err != nil {
+			return fmt.Errorf("main : Failed loading host limits : %v", err)
+		}
+	}
+
+	hostLimiter, err := sitehealthchecker.NewHostLimiter(sitehealthchecker.HostLimit{
+		MaxInFlight: cfg.HostMaxInFlight,
+		Rate:        cfg.HostRate,
+		Burst:       cfg.HostBurst,
+	}, hostLimits)
+	if err != nil {
+		return fmt.Errorf("main : Failed configuring host limits : %v", err)
+	}
+	sitehealthchecker.UseHostLimiter(hostLimiter)
 
 	// =========================================================================
 	// App Starting
@@ -102,7 +291,7 @@ func run() error {
 
 	server := http.Server{
 		Addr:    cfg.Host,
-		Handler: httphandlers.Routes(log, &str, broker, cfg.SSE),
+		Handler: httphandlers.Routes(log, str, broker, cfg.SSE),
 	}
 
 	// Make a channel to listen for errors coming from the listener. Use a
@@ -115,19 +304,64 @@ func run() error {
 		serverErrors <- server.ListenAndServe()
 	}()
 
-	// Run a ticker that will check the health of all sites every 15 seconds
-	ticker := time.NewTicker(15 * time.Second)
+	// Run the checks on a fixed set of workers, publishing the pool metrics
+	// along with the other expvars. Clients are told to refresh once a result
+	// is recorded. The workers do not wait on the broker, an event already
+	// pending refreshes the clients all the same.
+	pool := sitehealthchecker.NewPool(str, checkTimeout, cfg.Workers, cfg.QueueSize)
+	pool.OnResult = func(s sitestore.Site) {
+		event := []byte("done")
+		if st, err := str.Get(s.ID); err == nil && st.LastResult != nil && st.LastResult.ContentChanged {
+			log.Printf("main : pool : Site %d content changed", s.ID)
+			event = []byte(fmt.Sprintf("changed %d", s.ID))
+		}
+
+		select {
+		case broker.Notifier <- event:
+		default:
+		}
+	}
+	expvar.Publish("pool", expvar.Func(func() interface{} { return pool.Stats() }))
+
+	// Run a scheduler that will queue a check of every site once its interval elapsed
+	scheduler := sitehealthchecker.NewScheduler(
+		pool,
+		sitehealthchecker.DefaultInterval,
+		time.Duration(cfg.LookbackPeriod)*time.Second,
+	)
+	scheduler.Stagger = cfg.Stagger
+	scheduler.Jitter = time.Duration(cfg.Jitter) * time.Second
+	checksCtx, cancelChecks := context.WithCancel(context.Background())
+	defer cancelChecks()
+	checksDone := make(chan struct{})
 
 	go func() {
+		defer close(checksDone)
 		log.Printf("main : Site health checker running")
-		for {
-			<-ticker.C
-			log.Printf("main : ticker : Run health checks")
-			sitehealthchecker.ParallelHealthChecks(&str, 800*time.Millisecond, cfg.LookbackPeriod)
-			broker.Notifier <- []byte("done")
-		}
+		scheduler.Run(checksCtx, time.Second, func(sites []sitestore.Site) {
+			log.Printf("main : scheduler : Queued health checks on %d sites", len(sites))
+		})
+
+		// Record the checks still queued once the scheduler stopped
+		pool.Close()
 	}()
 
+	// Periodically snapshot the memory store so that a crash loses at most one interval
+	var snapshotTicker *time.Ticker
+	if snapshotStore != nil {
+		snapshotTicker = time.NewTicker(time.Duration(cfg.SnapshotInterval) * time.Second)
+
+		go func() {
+			log.Printf("main : Site memory store snapshot running")
+			for {
+				<-snapshotTicker.C
+				if err := snapshotStore.Snapshot(cfg.SnapshotPath); err != nil {
+					log.Printf("main : snapshot : Failed saving snapshot : %v", err)
+				}
+			}
+		}()
+	}
+
 	// =========================================================================
 	// Shutdown
 
@@ -138,7 +372,25 @@ func run() error {
 
 	case sig := <-shutdown:
 		log.Printf("main : %v : Shuttting down site health checker", sig)
-		ticker.Stop()
+
+		// Cancel the checks in flight, they are recorded as cancelled once
+		// their probes return. A probe that ignores cancellation is not
+		// waited on for longer than the drain timeout.
+		cancelChecks()
+		drainTimeout := time.Duration(cfg.DrainTimeout) * time.Second
+		select {
+		case <-checksDone:
+		case <-time.After(drainTimeout):
+			log.Printf("main : %v : Site health checks did not drain in %v", sig, drainTimeout)
+		}
+
+		if snapshotStore != nil {
+			log.Printf("main : %v : Saving site memory store snapshot", sig)
+			snapshotTicker.Stop()
+			if err := snapshotStore.Snapshot(cfg.SnapshotPath); err != nil {
+				log.Printf("main : Failed saving snapshot : %v", err)
+			}
+		}
 
 		log.Printf("main : %v : Shuttting down app", sig)
 
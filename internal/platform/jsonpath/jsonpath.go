@@ -0,0 +1,172 @@
+// Package jsonpath evaluates a small subset of JSONPath against JSON documents.
+//
+// An expression is a path made of `$` followed by `.name`, `['name']` or
+// `[index]` segments, optionally compared to a JSON literal with `==` or `!=`:
+//
+//	$.status == "ok"
+//	$.checks[0].healthy != false
+//	$.data['build-id']
+//
+// An expression without a comparison only asserts that the path exists.
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expr represents a compiled expression
+type Expr struct {
+	raw      string
+	path     []segment
+	op       string
+	expected interface{}
+}
+
+type segment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// Compile parses an expression
+func Compile(expr string) (*Expr, error) {
+	e := Expr{raw: strings.TrimSpace(expr)}
+
+	// The comparison follows the end of the path, so that operators
+	// appearing inside bracketed member names or the expected literal are
+	// left alone
+	path, rest, err := parsePath(e.raw)
+	if err != nil {
+		return nil, err
+	}
+	e.path = path
+
+	if rest = strings.TrimSpace(rest); rest != "" {
+		e.op = rest[:min(2, len(rest))]
+		if e.op != "==" && e.op != "!=" {
+			return nil, errors.New("JSONPath comparison must be == or !=")
+		}
+
+		if err := json.Unmarshal([]byte(strings.TrimSpace(rest[2:])), &e.expected); err != nil {
+			return nil, fmt.Errorf("JSONPath expected value must be a JSON literal: %v", err)
+		}
+	}
+
+	return &e, nil
+}
+
+// parsePath parses the path at the start of s, returning it along with what
+// follows it
+func parsePath(s string) ([]segment, string, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, "", errors.New("JSONPath must start with $")
+	}
+	s = s[1:]
+
+	var path []segment
+	for len(s) > 0 && !strings.ContainsAny(s[:1], " \t=!") {
+		switch {
+		case s[0] == '.':
+			end := strings.IndexAny(s[1:], ".[ \t=!")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			key := s[1 : end+1]
+			if key == "" {
+				return nil, "", errors.New("JSONPath has an empty member name")
+			}
+			path = append(path, segment{key: key})
+			s = s[end+1:]
+
+		case strings.HasPrefix(s, "['"):
+			end := strings.Index(s, "']")
+			if end < 0 {
+				return nil, "", errors.New("JSONPath has an unterminated member name")
+			}
+			path = append(path, segment{key: s[2:end]})
+			s = s[end+2:]
+
+		case s[0] == '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, "", errors.New("JSONPath has an unterminated index")
+			}
+			idx, err := strconv.Atoi(s[1:end])
+			if err != nil || idx < 0 {
+				return nil, "", errors.New("JSONPath index must be a positive number")
+			}
+			path = append(path, segment{index: idx, isIdx: true})
+			s = s[end+1:]
+
+		default:
+			return nil, "", fmt.Errorf("JSONPath has an unexpected character %q", s[0])
+		}
+	}
+
+	return path, s, nil
+}
+
+// Match evaluates the expression against a JSON document. It returns nil when
+// the expression holds, and an error describing why it does not otherwise.
+func (e *Expr) Match(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("Body is not valid JSON: %v", err)
+	}
+
+	v, err := e.lookup(doc)
+	if err != nil {
+		return err
+	}
+
+	switch e.op {
+	case "==":
+		if !reflect.DeepEqual(v, e.expected) {
+			return fmt.Errorf("%s: got %s", e.raw, literal(v))
+		}
+	case "!=":
+		if reflect.DeepEqual(v, e.expected) {
+			return fmt.Errorf("%s: got %s", e.raw, literal(v))
+		}
+	}
+
+	return nil
+}
+
+func (e *Expr) lookup(doc interface{}) (interface{}, error) {
+	v := doc
+	for _, seg := range e.path {
+		if seg.isIdx {
+			arr, ok := v.([]interface{})
+			if !ok || seg.index >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d not found", e.raw, seg.index)
+			}
+			v = arr[seg.index]
+			continue
+		}
+
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: member %q not found", e.raw, seg.key)
+		}
+		if v, ok = obj[seg.key]; !ok {
+			return nil, fmt.Errorf("%s: member %q not found", e.raw, seg.key)
+		}
+	}
+
+	return v, nil
+}
+
+func literal(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,72 @@
+package jsonpath
+
+import "testing"
+
+const doc = `{
+	"status": "ok",
+	"uptime": 42,
+	"checks": [{"name": "db", "healthy": true}, {"name": "cache", "healthy": false}],
+	"data": {"build-id": "abc", "a=b": 1, "ready!": true}
+}`
+
+func TestMatch(t *testing.T) {
+	var testCases = []struct {
+		name  string
+		expr  string
+		match bool
+	}{
+		{name: "Comparing a string", expr: `$.status == "ok"`, match: true},
+		{name: "Comparing a mismatching string", expr: `$.status == "down"`, match: false},
+		{name: "Comparing a number", expr: `$.uptime == 42`, match: true},
+		{name: "Indexing an array", expr: `$.checks[1].healthy == false`, match: true},
+		{name: "Negating a comparison", expr: `$.checks[0].healthy != true`, match: false},
+		{name: "Quoting a member name", expr: `$.data['build-id'] == "abc"`, match: true},
+		{name: "Asserting a path exists", expr: `$.checks[0].name`, match: true},
+		{name: "Asserting a missing path", expr: `$.version`, match: false},
+		{name: "Indexing out of range", expr: `$.checks[5]`, match: false},
+		{name: "Comparing against an operator literal", expr: `$.status != "=="`, match: true},
+		{name: "Quoting a member name with an operator", expr: `$.data['a=b'] == 1`, match: true},
+		{name: "Quoting a member name with a negation", expr: `$.data['ready!'] != false`, match: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := Compile(tc.expr)
+			if err != nil {
+				t.Fatalf("Error is not expected. Got err: %v", err)
+			}
+
+			err = e.Match([]byte(doc))
+			if tc.match && err != nil {
+				t.Errorf("Expected %v to match but got %v", tc.expr, err)
+			}
+
+			if !tc.match && err == nil {
+				t.Errorf("Expected %v not to match but it did", tc.expr)
+			}
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		`status == "ok"`,
+		`$.status = "ok"`,
+		`$.status == ok`,
+		`$..status`,
+		`$.checks[first]`,
+		`$.data['build-id`,
+		`$.status "ok"`,
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Expected %v to be invalid but it compiled", expr)
+		}
+	}
+}
+
+func TestMatch_InvalidJSON(t *testing.T) {
+	e, _ := Compile(`$.status == "ok"`)
+	if err := e.Match([]byte("<html>error</html>")); err == nil {
+		t.Errorf("Expected a non JSON body not to match")
+	}
+}
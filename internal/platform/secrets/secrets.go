@@ -0,0 +1,89 @@
+// Package secrets resolves references to secrets so that site configuration
+// never has to hold credentials in plain text.
+//
+// A reference is written `${env:NAME}` to read the NAME environment variable,
+// or `${secret:name}` to read the name key of the secrets file, a flat JSON
+// object of strings.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var referenceRe = regexp.MustCompile(`\$\{(env|secret):([A-Za-z0-9_.\-]+)\}`)
+
+// Resolver resolves secret references
+type Resolver struct {
+	file map[string]string
+}
+
+// NewResolver constructs a Resolver that only knows about environment variables
+func NewResolver() *Resolver {
+	return &Resolver{file: make(map[string]string)}
+}
+
+// Load constructs a Resolver backed by the secrets file at path
+func Load(path string) (*Resolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewResolver()
+	if err := json.Unmarshal(data, &r.file); err != nil {
+		return nil, fmt.Errorf("secrets : Failed parsing secrets file : %v", err)
+	}
+
+	return r, nil
+}
+
+// Expand replaces every reference in s with the secret it points to
+func (r *Resolver) Expand(s string) (string, error) {
+	var missing error
+	expanded := referenceRe.ReplaceAllStringFunc(s, func(ref string) string {
+		m := referenceRe.FindStringSubmatch(ref)
+
+		var v string
+		var found bool
+		switch m[1] {
+		case "env":
+			v, found = os.LookupEnv(m[2])
+		case "secret":
+			v, found = r.file[m[2]]
+		}
+
+		if !found && missing == nil {
+			missing = fmt.Errorf("Secret %s is not set", ref)
+		}
+		return v
+	})
+
+	return expanded, missing
+}
+
+// IsReference reports whether s is a single secret reference
+func IsReference(s string) bool {
+	s = strings.TrimSpace(s)
+	loc := referenceRe.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// prefixRe matches the scheme or name that may precede a reference, such as
+// `Bearer ` or `sid=`
+var prefixRe = regexp.MustCompile(`^[A-Za-z0-9_\-]+[ =]$`)
+
+// IsPrefixedReference reports whether s is a single secret reference, alone
+// or preceded by a scheme or name like `Bearer ${env:TOKEN}` or `sid=${secret:sid}`
+func IsPrefixedReference(s string) bool {
+	s = strings.TrimSpace(s)
+	loc := referenceRe.FindStringIndex(s)
+	if loc == nil || loc[1] != len(s) {
+		return false
+	}
+
+	return loc[0] == 0 || prefixRe.MatchString(s[:loc[0]])
+}
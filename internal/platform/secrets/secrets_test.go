@@ -0,0 +1,108 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	os.Setenv("GOHEALTH_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("GOHEALTH_TEST_TOKEN")
+
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	os.WriteFile(path, []byte(`{"api_key": "k3y"}`), 0600)
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatalf("Error is not expected. Got err: %v", err)
+	}
+
+	var testCases = []struct {
+		name   string
+		input  string
+		exp    string
+		hasErr bool
+	}{
+		{
+			name:  "Expanding an environment variable",
+			input: "Bearer ${env:GOHEALTH_TEST_TOKEN}",
+			exp:   "Bearer s3cr3t",
+		},
+		{
+			name:  "Expanding a secrets file key",
+			input: `{"key": "${secret:api_key}"}`,
+			exp:   `{"key": "k3y"}`,
+		},
+		{
+			name:  "Expanding plain text",
+			input: "gohealth/1.0",
+			exp:   "gohealth/1.0",
+		},
+		{
+			name:   "Expanding a missing secret",
+			input:  "${secret:password}",
+			hasErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := r.Expand(tc.input)
+
+			if tc.hasErr {
+				if err == nil {
+					t.Errorf("Expected to return an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Error is not expected. Got err: %v", err)
+			}
+
+			if v != tc.exp {
+				t.Errorf("Expected %v but got %v", tc.exp, v)
+			}
+		})
+	}
+}
+
+func TestIsReference(t *testing.T) {
+	var testCases = []struct {
+		input string
+		exp   bool
+	}{
+		{input: "${env:TOKEN}", exp: true},
+		{input: "${secret:api_key}", exp: true},
+		{input: "Bearer ${env:TOKEN}", exp: false},
+		{input: "hunter2", exp: false},
+		{input: "${vault:TOKEN}", exp: false},
+	}
+
+	for _, tc := range testCases {
+		if r := IsReference(tc.input); r != tc.exp {
+			t.Errorf("Expected IsReference(%q) to be %v but got %v", tc.input, tc.exp, r)
+		}
+	}
+}
+
+func TestIsPrefixedReference(t *testing.T) {
+	var testCases = []struct {
+		input string
+		exp   bool
+	}{
+		{input: "${env:TOKEN}", exp: true},
+		{input: "Bearer ${env:TOKEN}", exp: true},
+		{input: "sid=${secret:sid}", exp: true},
+		{input: "Bearer hunter2", exp: false},
+		{input: "${env:TOKEN} hunter2", exp: false},
+		{input: "a=hunter2; b=${secret:sid}", exp: false},
+	}
+
+	for _, tc := range testCases {
+		if r := IsPrefixedReference(tc.input); r != tc.exp {
+			t.Errorf("Expected IsPrefixedReference(%q) to be %v but got %v", tc.input, tc.exp, r)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package sitestore
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/levady/gohealth/internal/platform/jsonpath"
+)
+
+const (
+	// ContainsAssertion asserts that the body contains Value
+	ContainsAssertion = "contains"
+	// NotContainsAssertion asserts that the body does not contain Value
+	NotContainsAssertion = "not_contains"
+	// RegexAssertion asserts that the body matches the regular expression Value
+	RegexAssertion = "regex"
+	// JSONPathAssertion asserts that the JSON body satisfies the expression Value,
+	// e.g. `$.status == "ok"`
+	JSONPathAssertion = "jsonpath"
+)
+
+// BodyAssertion represents an assertion on the response body of a site
+type BodyAssertion struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Validate checks that the assertion can be evaluated
+func (a BodyAssertion) Validate() error {
+	switch a.Type {
+	case ContainsAssertion, NotContainsAssertion:
+		if a.Value == "" {
+			return errors.New("Body assertion value must not be empty")
+		}
+	case RegexAssertion:
+		if _, err := regexp.Compile(a.Value); err != nil {
+			return errors.New("Body assertion regex is not valid")
+		}
+	case JSONPathAssertion:
+		if _, err := jsonpath.Compile(a.Value); err != nil {
+			return err
+		}
+	default:
+		return errors.New("Body assertion type must be contains, not_contains, regex or jsonpath")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package sitestore
+
+import "testing"
+
+func TestBodyAssertion_Validate(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		assertion BodyAssertion
+		hasErr    bool
+	}{
+		{
+			name:      "Validating a contains assertion",
+			assertion: BodyAssertion{Type: ContainsAssertion, Value: "ok"},
+		},
+		{
+			name:      "Validating an empty contains assertion",
+			assertion: BodyAssertion{Type: NotContainsAssertion},
+			hasErr:    true,
+		},
+		{
+			name:      "Validating a regex assertion",
+			assertion: BodyAssertion{Type: RegexAssertion, Value: `v\d+`},
+		},
+		{
+			name:      "Validating an invalid regex assertion",
+			assertion: BodyAssertion{Type: RegexAssertion, Value: `v(\d+`},
+			hasErr:    true,
+		},
+		{
+			name:      "Validating a jsonpath assertion",
+			assertion: BodyAssertion{Type: JSONPathAssertion, Value: `$.status == "ok"`},
+		},
+		{
+			name:      "Validating an invalid jsonpath assertion",
+			assertion: BodyAssertion{Type: JSONPathAssertion, Value: `status == ok`},
+			hasErr:    true,
+		},
+		{
+			name:      "Validating an unknown assertion type",
+			assertion: BodyAssertion{Type: "xpath", Value: "//status"},
+			hasErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.assertion.Validate()
+
+			if tc.hasErr && err == nil {
+				t.Errorf("Expected to return an error but got nil")
+			}
+
+			if !tc.hasErr && err != nil {
+				t.Errorf("Error is not expected. Got err: %v", err)
+			}
+		})
+	}
+}
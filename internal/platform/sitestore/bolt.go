@@ -0,0 +1,311 @@
+package sitestore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	sitesBucket   = []byte("sites")
+	historyBucket = []byte("history")
+)
+
+// BoltStore represent a file-backed data store for sites. The sites bucket
+// sequence doubles as the ID tracker so that IDs survive restarts.
+type BoltStore struct {
+	db       *bolt.DB
+	revision int64
+}
+
+// NewBoltStore opens the bolt database at path, creating it if needed
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(sitesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(historyBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the underlying database
+func (str *BoltStore) Close() error {
+	return str.db.Close()
+}
+
+// List returns a collection of sites. Sites that cannot be read are left out.
+func (str *BoltStore) List() []Site {
+	return str.list(func(Site) bool { return true })
+}
+
+// ListFilter returns a collection of sites filtered by their last updated at in seconds
+func (str *BoltStore) ListFilter(lookbackPeriod int) []Site {
+	return str.list(func(st Site) bool { return isStale(st, lookbackPeriod) })
+}
+
+// list returns the sites matching keep. Keys are big endian IDs so the
+// cursor already walks them in ID order.
+func (str *BoltStore) list(keep func(Site) bool) []Site {
+	sites := make([]Site, 0)
+	str.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sitesBucket).ForEach(func(_, v []byte) error {
+			var st Site
+			if err := json.Unmarshal(v, &st); err != nil {
+				return nil
+			}
+			if keep(st) {
+				sites = append(sites, st)
+			}
+			return nil
+		})
+	})
+
+	return sites
+}
+
+// Get returns a single site
+func (str *BoltStore) Get(siteID int) (Site, error) {
+	var st Site
+	err := str.db.View(func(tx *bolt.Tx) error {
+		var err error
+		st, err = getSite(tx, siteID)
+		return err
+	})
+
+	return st, err
+}
+
+// Add adds a single site to the store
+func (str *BoltStore) Add(st Site) error {
+	st, err := validate(st)
+	if err != nil {
+		return err
+	}
+
+	added := false
+	err = str.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sitesBucket)
+
+		// Validate duplicate URL
+		duplicate := errors.New("duplicate")
+		err := b.ForEach(func(_, v []byte) error {
+			var site Site
+			if err := json.Unmarshal(v, &site); err == nil && site.URL == st.URL {
+				return duplicate
+			}
+			return nil
+		})
+		if err == duplicate {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		st.ID = int(id)
+		added = true
+
+		return putSite(tx, st)
+	})
+	if err == nil && added {
+		atomic.AddInt64(&str.revision, 1)
+	}
+
+	return err
+}
+
+// UpdateHealth update the health status of a site
+func (str *BoltStore) UpdateHealth(siteID int, status int) error {
+	return str.db.Update(func(tx *bolt.Tx) error {
+		st, err := getSite(tx, siteID)
+		if err != nil {
+			return err
+		}
+
+		st.Status = status
+		st.UpdatedAt = time.Now()
+		return putSite(tx, st)
+	})
+}
+
+// UpdateResult records the result of a health check on a site
+func (str *BoltStore) UpdateResult(siteID int, res CheckResult) error {
+	if res.CheckedAt.IsZero() {
+		res.CheckedAt = time.Now()
+	}
+
+	return str.db.Update(func(tx *bolt.Tx) error {
+		st, err := getSite(tx, siteID)
+		if err != nil {
+			return err
+		}
+
+		applyResult(&st, &res)
+
+		h, err := tx.Bucket(historyBucket).CreateBucketIfNotExists(itob(siteID))
+		if err != nil {
+			return err
+		}
+
+		seq, err := h.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		if err := h.Put(itob(int(seq)), v); err != nil {
+			return err
+		}
+
+		// Results are keyed sequentially, so dropping the one that just fell
+		// out of the window keeps the history bounded
+		if seq > HistorySize {
+			if err := h.Delete(itob(int(seq) - HistorySize)); err != nil {
+				return err
+			}
+		}
+
+		recent, err := recentResults(h, res.CheckedAt.Add(-FlapWindow))
+		if err != nil {
+			return err
+		}
+		st.Flapping = isFlapping(recent, res.CheckedAt)
+
+		return putSite(tx, st)
+	})
+}
+
+// recentResults returns the results of a history bucket recorded since from,
+// oldest first
+func recentResults(h *bolt.Bucket, from time.Time) ([]CheckResult, error) {
+	var results []CheckResult
+	c := h.Cursor()
+	for k, v := c.Last(); k != nil; k, v = c.Prev() {
+		var res CheckResult
+		if err := json.Unmarshal(v, &res); err != nil {
+			return nil, err
+		}
+
+		if res.CheckedAt.Before(from) {
+			break
+		}
+		results = append([]CheckResult{res}, results...)
+	}
+
+	return results, nil
+}
+
+// History returns the check results of a site that were recorded between from
+// and to, oldest first. A zero from or to leaves that end of the window open.
+func (str *BoltStore) History(siteID int, from, to time.Time) ([]CheckResult, error) {
+	results := make([]CheckResult, 0)
+	err := str.db.View(func(tx *bolt.Tx) error {
+		if _, err := getSite(tx, siteID); err != nil {
+			return err
+		}
+
+		h := tx.Bucket(historyBucket).Bucket(itob(siteID))
+		if h == nil {
+			return nil
+		}
+
+		return h.ForEach(func(_, v []byte) error {
+			var res CheckResult
+			if err := json.Unmarshal(v, &res); err != nil {
+				return err
+			}
+			if !from.IsZero() && res.CheckedAt.Before(from) {
+				return nil
+			}
+			if !to.IsZero() && res.CheckedAt.After(to) {
+				return nil
+			}
+			results = append(results, res)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Delete deletes a site from the store
+func (str *BoltStore) Delete(siteID int) error {
+	err := str.db.Update(func(tx *bolt.Tx) error {
+		if _, err := getSite(tx, siteID); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(sitesBucket).Delete(itob(siteID)); err != nil {
+			return err
+		}
+
+		err := tx.Bucket(historyBucket).DeleteBucket(itob(siteID))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+	if err == nil {
+		atomic.AddInt64(&str.revision, 1)
+	}
+
+	return err
+}
+
+// Revision returns a number that changes whenever a site is added or deleted
+func (str *BoltStore) Revision() int64 {
+	return atomic.LoadInt64(&str.revision)
+}
+
+func getSite(tx *bolt.Tx, siteID int) (Site, error) {
+	var st Site
+
+	v := tx.Bucket(sitesBucket).Get(itob(siteID))
+	if v == nil {
+		return st, errors.New("Site does not exist")
+	}
+
+	err := json.Unmarshal(v, &st)
+	return st, err
+}
+
+func putSite(tx *bolt.Tx, st Site) error {
+	v, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(sitesBucket).Put(itob(st.ID), v)
+}
+
+// itob encodes an ID as a big endian key so that keys sort by ID
+func itob(id int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
@@ -0,0 +1,149 @@
+package sitestore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestBoltStore(t *testing.T, path string) *BoltStore {
+	str, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("Failed opening bolt store. Err: %v", err)
+	}
+
+	return str
+}
+
+func TestBoltStore_Add(t *testing.T) {
+	str := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer str.Close()
+
+	str.Add(Site{URL: "https://golang.org/doc/articles/wiki/"})
+	str.Add(Site{URL: "https://google.com/"})
+	str.Add(Site{URL: "https://golang.org/doc/articles/wiki/"})
+
+	if err := str.Add(Site{URL: "ftp://websiteaddress.com"}); err == nil {
+		t.Errorf("Expected to return an error but got nil")
+	}
+
+	sites := str.List()
+	if len(sites) != 2 {
+		t.Fatalf("Expected Sites length of %d, but it was %d instead.", 2, len(sites))
+	}
+
+	if s := sites[1]; s.ID != 2 || s.URL != "https://google.com/" || s.CheckType != HTTPCheck {
+		t.Errorf("Expected the second site to be google.com with ID 2, but it was %+v.", s)
+	}
+}
+
+func TestBoltStore_Revision(t *testing.T) {
+	str := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer str.Close()
+
+	rev := str.Revision()
+	str.Add(Site{URL: "https://google.com/"})
+	if r := str.Revision(); r == rev {
+		t.Fatalf("Expected adding a site to change the revision but it stayed %d", r)
+	}
+
+	rev = str.Revision()
+	str.Add(Site{URL: "https://google.com/"})
+	str.UpdateResult(1, CheckResult{Status: Healthy})
+	if r := str.Revision(); r != rev {
+		t.Errorf("Expected a duplicate and a result not to change the revision but it went from %d to %d", rev, r)
+	}
+
+	str.Delete(1)
+	if r := str.Revision(); r == rev {
+		t.Errorf("Expected deleting a site to change the revision but it stayed %d", r)
+	}
+}
+
+func TestBoltStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gohealth.db")
+
+	str := newTestBoltStore(t, path)
+	str.Add(site1)
+	str.Add(site2)
+	str.Delete(2)
+	str.UpdateResult(1, CheckResult{Status: Unhealthy, StatusCode: 503})
+	str.Close()
+
+	str = newTestBoltStore(t, path)
+	defer str.Close()
+
+	s, err := str.Get(1)
+	if err != nil {
+		t.Fatalf("Expected site to survive a restart. Err: %v", err)
+	}
+
+	if s.Status != Unhealthy || s.LastResult == nil || s.LastResult.StatusCode != 503 {
+		t.Errorf("Expected site result to survive a restart but got %+v", s)
+	}
+
+	if results, _ := str.History(1, time.Time{}, time.Time{}); len(results) != 1 {
+		t.Errorf("Expected history to survive a restart but got %v", results)
+	}
+
+	str.Add(site3)
+	if s, _ := str.Get(3); s.URL != site3.URL {
+		t.Errorf("Expected IDs to keep incrementing after a restart but got %+v", str.List())
+	}
+}
+
+func TestBoltStore_ListFilter(t *testing.T) {
+	str := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer str.Close()
+
+	str.Add(Site{URL: "https://zempag.com", UpdatedAt: time.Now().Add(time.Duration(-12) * time.Second)})
+	str.Add(Site{URL: "https://www.google.com", UpdatedAt: time.Now().Add(time.Duration(-27) * time.Second)})
+	str.Add(Site{URL: "https://koprol.com"})
+
+	sites := str.ListFilter(15)
+	if len(sites) != 2 {
+		t.Fatalf("Expected result length to 2 but it was %v", len(sites))
+	}
+
+	if s := sites[0]; s.URL != "https://www.google.com" {
+		t.Errorf("Expected the first site in the array to be google.com, but it was %v.", s.URL)
+	}
+}
+
+func TestBoltStore_History(t *testing.T) {
+	str := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer str.Close()
+
+	str.Add(site1)
+
+	now := time.Now()
+	for i := HistorySize + 10; i > 0; i-- {
+		str.UpdateResult(1, CheckResult{Status: Healthy, CheckedAt: now.Add(time.Duration(-i) * time.Minute)})
+	}
+
+	results, err := str.History(1, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Error is not expected. Got err: %v", err)
+	}
+
+	if len(results) != HistorySize {
+		t.Errorf("Expected %d results but got %d", HistorySize, len(results))
+	}
+
+	results, _ = str.History(1, now.Add(time.Duration(-30)*time.Minute), now.Add(time.Duration(-21)*time.Minute))
+	if len(results) != 10 {
+		t.Errorf("Expected %d results but got %d", 10, len(results))
+	}
+
+	if err := str.Delete(1); err != nil {
+		t.Fatalf("Error is not expected. Got err: %v", err)
+	}
+
+	if _, err := str.History(1, time.Time{}, time.Time{}); err == nil {
+		t.Errorf("Expected history of a deleted site to return an error but got nil")
+	}
+
+	if err := str.Delete(1); err == nil {
+		t.Errorf("Expected deleting a deleted site to return an error but got nil")
+	}
+}
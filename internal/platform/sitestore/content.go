@@ -0,0 +1,45 @@
+package sitestore
+
+import (
+	"errors"
+	"regexp"
+)
+
+// ContentOptions represents how the content of a site is watched for changes.
+// The response body is narrowed to the first match of the Extract regular
+// expression, or to its first group, then every match of the Strip regular
+// expressions is removed before hashing, so that volatile sections such as
+// timestamps or CSRF tokens do not count as changes.
+type ContentOptions struct {
+	Extract string   `json:"extract,omitempty"`
+	Strip   []string `json:"strip,omitempty"`
+}
+
+// Validate checks that every regular expression is valid
+func (o ContentOptions) Validate() error {
+	if _, err := regexp.Compile(o.Extract); err != nil {
+		return errors.New("Content extraction regex is not valid")
+	}
+
+	for _, s := range o.Strip {
+		if _, err := regexp.Compile(s); err != nil {
+			return errors.New("Content stripping regex is not valid")
+		}
+	}
+
+	return nil
+}
+
+// trackContent compares the content hash of a result with the last one stored
+// on the site, marking the result as changed when they differ
+func trackContent(st *Site, res *CheckResult) {
+	if res.ContentHash == "" {
+		return
+	}
+
+	if st.ContentHash != "" && st.ContentHash != res.ContentHash {
+		res.ContentChanged = true
+		res.PreviousContentHash = st.ContentHash
+	}
+	st.ContentHash = res.ContentHash
+}
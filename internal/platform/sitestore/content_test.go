@@ -0,0 +1,73 @@
+package sitestore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateResult_ContentChange(t *testing.T) {
+	mem := NewStore()
+	bolt := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer bolt.Close()
+
+	var stores = []struct {
+		name string
+		str  Store
+	}{
+		{name: "Memory store", str: &mem},
+		{name: "Bolt store", str: bolt},
+	}
+
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			str := s.str
+			str.Add(Site{URL: "https://zempag.com", Content: &ContentOptions{Strip: []string{`\d{4}-\d{2}-\d{2}`}}})
+
+			// A failed check without a hash in between does not reset the last hash seen
+			str.UpdateResult(1, CheckResult{Status: Healthy, ContentHash: "aaa"})
+			str.UpdateResult(1, CheckResult{Status: Unhealthy})
+			str.UpdateResult(1, CheckResult{Status: Healthy, ContentHash: "aaa"})
+			str.UpdateResult(1, CheckResult{Status: Healthy, ContentHash: "bbb"})
+
+			history, _ := str.History(1, time.Time{}, time.Time{})
+			if len(history) != 4 {
+				t.Fatalf("Expected 4 results in history but got %d", len(history))
+			}
+
+			for i, res := range history[:3] {
+				if res.ContentChanged {
+					t.Errorf("Expected result %d not to be a content change but got %+v", i, res)
+				}
+			}
+
+			if last := history[3]; !last.ContentChanged || last.PreviousContentHash != "aaa" || last.ContentHash != "bbb" {
+				t.Errorf("Expected a content change from aaa to bbb but got %+v", last)
+			}
+
+			if site, _ := str.Get(1); site.ContentHash != "bbb" {
+				t.Errorf("Expected site content hash to be bbb but got %q", site.ContentHash)
+			}
+		})
+	}
+}
+
+func TestContentOptions_Validate(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		opts   ContentOptions
+		hasErr bool
+	}{
+		{name: "Validating regexes", opts: ContentOptions{Extract: `<main>(?s:(.*))</main>`, Strip: []string{`csrf="\w+"`}}},
+		{name: "Validating an invalid extraction", opts: ContentOptions{Extract: `(<main>`}, hasErr: true},
+		{name: "Validating an invalid stripping", opts: ContentOptions{Strip: []string{`[0-9`}}, hasErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.opts.Validate(); (err != nil) != tc.hasErr {
+				t.Errorf("Expected error: %v but got %v", tc.hasErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package sitestore
+
+import (
+	"errors"
+
+	"github.com/levady/gohealth/internal/platform/secrets"
+)
+
+// DataStoreOptions represents how a data store check connects. DSN must be a
+// secret reference to the connection string, which usually holds credentials,
+// see package secrets. The site URL is used as the connection string when DSN
+// is empty.
+type DataStoreOptions struct {
+	DSN string `json:"dsn,omitempty"`
+}
+
+// Validate checks that the DSN is not in plain text
+func (o DataStoreOptions) Validate() error {
+	if o.DSN != "" && !secrets.IsReference(o.DSN) {
+		return errors.New("Data store DSN must be a secret reference like ${env:NAME} or ${secret:name}")
+	}
+
+	return nil
+}
+
+func isDataStore(checkType string) bool {
+	return checkType == PostgresCheck || checkType == MySQLCheck || checkType == RedisCheck
+}
@@ -0,0 +1,63 @@
+package sitestore
+
+import "time"
+
+var (
+	// FlapWindow is how far back the raw statuses of a site are looked at to
+	// detect flapping
+	FlapWindow = 10 * time.Minute
+	// FlapChanges is the number of raw status changes within FlapWindow from
+	// which a site is flapping
+	FlapChanges = 5
+)
+
+// debounce records the raw status of a check result on the site, and only
+// moves the site status once the failure or recovery threshold is reached
+func debounce(st *Site, res CheckResult) {
+	st.RawStatus = res.Status
+
+	if res.Status == Unhealthy {
+		st.ConsecutiveFailures++
+		st.ConsecutiveSuccesses = 0
+		if st.ConsecutiveFailures >= threshold(st.FailThreshold) {
+			st.Status = Unhealthy
+		}
+		return
+	}
+
+	st.ConsecutiveSuccesses++
+	st.ConsecutiveFailures = 0
+	if st.Status != Unhealthy || st.ConsecutiveSuccesses >= threshold(st.RecoverThreshold) {
+		st.Status = res.Status
+	}
+}
+
+// threshold returns the number of consecutive results a site threshold stands
+// for, 0 meaning a single one
+func threshold(n int) int {
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// isFlapping reports whether the raw status changed at least FlapChanges times
+// among the completed results, oldest first, recorded within FlapWindow before now
+func isFlapping(results []CheckResult, now time.Time) bool {
+	changes := 0
+	var prev *CheckResult
+	for i := range results {
+		res := &results[i]
+		if res.Status == Cancelled || res.CheckedAt.Before(now.Add(-FlapWindow)) {
+			continue
+		}
+
+		if prev != nil && prev.Status != res.Status {
+			changes++
+		}
+		prev = res
+	}
+
+	return changes >= FlapChanges
+}
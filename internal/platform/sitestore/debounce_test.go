@@ -0,0 +1,126 @@
+package sitestore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateResult_Debounce(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		site      Site
+		results   []int
+		expStatus []int
+	}{
+		{
+			name:      "Updating without thresholds",
+			site:      Site{URL: "https://zempag.com"},
+			results:   []int{Healthy, Unhealthy, Healthy},
+			expStatus: []int{Healthy, Unhealthy, Healthy},
+		},
+		{
+			name:      "Updating with a failure threshold",
+			site:      Site{URL: "https://zempag.com", FailThreshold: 3},
+			results:   []int{Healthy, Unhealthy, Unhealthy, Healthy, Unhealthy, Unhealthy, Unhealthy},
+			expStatus: []int{Healthy, Healthy, Healthy, Healthy, Healthy, Healthy, Unhealthy},
+		},
+		{
+			name:      "Updating with a recovery threshold",
+			site:      Site{URL: "https://zempag.com", RecoverThreshold: 2},
+			results:   []int{Unhealthy, Healthy, Unhealthy, Healthy, Degraded},
+			expStatus: []int{Unhealthy, Unhealthy, Unhealthy, Unhealthy, Degraded},
+		},
+		{
+			name:      "Updating an unknown site with a failure threshold",
+			site:      Site{URL: "https://zempag.com", FailThreshold: 2},
+			results:   []int{Unhealthy, Unhealthy},
+			expStatus: []int{Unknown, Unhealthy},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewStore()
+			bolt := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+			defer bolt.Close()
+
+			for _, str := range []Store{&mem, bolt} {
+				str.Add(tc.site)
+
+				for i, status := range tc.results {
+					str.UpdateResult(1, CheckResult{Status: status})
+
+					s, _ := str.Get(1)
+					if s.Status != tc.expStatus[i] {
+						t.Errorf("%T: Expected status %d after result %d but got %d", str, tc.expStatus[i], i, s.Status)
+					}
+
+					if s.RawStatus != status {
+						t.Errorf("%T: Expected raw status %d after result %d but got %d", str, status, i, s.RawStatus)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateResult_Cancelled(t *testing.T) {
+	mem := NewStore()
+	bolt := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer bolt.Close()
+
+	for _, str := range []Store{&mem, bolt} {
+		checkedAt := time.Now().Add(-time.Minute).Round(time.Second)
+		str.Add(Site{URL: "https://zempag.com"})
+		str.UpdateResult(1, CheckResult{Status: Healthy, CheckedAt: checkedAt})
+		str.UpdateResult(1, CheckResult{Status: Cancelled, ErrorClass: CancelledError})
+
+		s, _ := str.Get(1)
+		if s.Status != Healthy || s.RawStatus != Healthy {
+			t.Errorf("%T: Expected a cancelled check to leave the site healthy but got %d (raw %d)", str, s.Status, s.RawStatus)
+		}
+
+		if s.LastResult == nil || s.LastResult.Status != Healthy {
+			t.Errorf("%T: Expected the last completed check to stay the last result but got %+v", str, s.LastResult)
+		}
+
+		if !s.UpdatedAt.Equal(checkedAt) {
+			t.Errorf("%T: Expected a cancelled check not to update the site but got %v", str, s.UpdatedAt)
+		}
+
+		if h, _ := str.History(1, time.Time{}, time.Time{}); len(h) != 2 {
+			t.Errorf("%T: Expected the cancelled check to be recorded in the history but got %+v", str, h)
+		}
+	}
+}
+
+func TestUpdateResult_Flapping(t *testing.T) {
+	mem := NewStore()
+	bolt := newTestBoltStore(t, filepath.Join(t.TempDir(), "gohealth.db"))
+	defer bolt.Close()
+
+	for _, str := range []Store{&mem, bolt} {
+		str.Add(Site{URL: "https://zempag.com"})
+
+		// Changes older than the window do not count
+		start := time.Now().Add(-2 * FlapWindow)
+		for i := 0; i < FlapChanges+1; i++ {
+			str.UpdateResult(1, CheckResult{Status: Healthy + i%2, CheckedAt: start.Add(time.Duration(i) * time.Second)})
+		}
+
+		now := time.Now()
+		for i := 0; i < FlapChanges; i++ {
+			str.UpdateResult(1, CheckResult{Status: Healthy + i%2, CheckedAt: now.Add(time.Duration(i) * time.Second)})
+
+			if s, _ := str.Get(1); s.Flapping {
+				t.Errorf("%T: Expected site not to be flapping after %d changes", str, i)
+			}
+		}
+
+		str.UpdateResult(1, CheckResult{Status: Healthy + FlapChanges%2, CheckedAt: now.Add(time.Minute)})
+		if s, _ := str.Get(1); !s.Flapping {
+			t.Errorf("%T: Expected site to be flapping after %d changes", str, FlapChanges)
+		}
+	}
+}
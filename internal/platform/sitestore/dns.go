@@ -0,0 +1,44 @@
+package sitestore
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// DNS record types a DNS check can resolve
+const (
+	ARecord     = "A"
+	AAAARecord  = "AAAA"
+	CNAMERecord = "CNAME"
+	MXRecord    = "MX"
+	TXTRecord   = "TXT"
+)
+
+var dnsRecordTypes = []string{ARecord, AAAARecord, CNAMERecord, MXRecord, TXTRecord}
+
+// DNSOptions represents the query of a DNS check. The name in the site URL is
+// resolved for RecordType records, A by default, against the Resolver
+// `host:port` address, the system resolver by default. Every Expect answer
+// must be among the resolved records for the site to be healthy.
+type DNSOptions struct {
+	RecordType string   `json:"record_type,omitempty"`
+	Resolver   string   `json:"resolver,omitempty"`
+	Expect     []string `json:"expect,omitempty"`
+}
+
+// Validate checks that the record type is supported and the resolver address
+// is valid
+func (o DNSOptions) Validate() error {
+	if o.RecordType != "" && !contains(dnsRecordTypes, o.RecordType) {
+		return errors.New("DNS record type must be one of " + strings.Join(dnsRecordTypes, ", "))
+	}
+
+	if o.Resolver != "" {
+		if _, _, err := net.SplitHostPort(o.Resolver); err != nil {
+			return errors.New("DNS resolver must be a host:port address")
+		}
+	}
+
+	return nil
+}
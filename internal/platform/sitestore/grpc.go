@@ -0,0 +1,7 @@
+package sitestore
+
+// GRPCOptions represents the call of a gRPC check. Service is the name of the
+// service whose health is checked, the server as a whole when empty.
+type GRPCOptions struct {
+	Service string `json:"service,omitempty"`
+}
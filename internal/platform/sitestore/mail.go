@@ -0,0 +1,8 @@
+package sitestore
+
+// MailOptions represents how a mail server is checked. With StartTLS the
+// server must offer STARTTLS and upgrade to a valid certificate, which is
+// then inspected like the certificate of a TLS check.
+type MailOptions struct {
+	StartTLS bool `json:"starttls,omitempty"`
+}
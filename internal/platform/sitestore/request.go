@@ -0,0 +1,63 @@
+package sitestore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/levady/gohealth/internal/platform/secrets"
+)
+
+// HTTPRequest represents how the request of an HTTP check is made. Header
+// values and the body may embed secret references, while BearerToken and
+// BasicAuthPassword must be a secret reference, and credential headers a
+// secret reference optionally preceded by a scheme, see package secrets.
+type HTTPRequest struct {
+	Method            string            `json:"method,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	BearerToken       string            `json:"bearer_token,omitempty"`
+	BasicAuthUser     string            `json:"basic_auth_user,omitempty"`
+	BasicAuthPassword string            `json:"basic_auth_password,omitempty"`
+}
+
+// credentialHeaderRe matches the names of the headers that carry credentials
+var credentialHeaderRe = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie)$|token|secret|password|api-?key|auth|session`)
+
+var httpMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"OPTIONS": true,
+}
+
+// Validate checks that the request can be made and holds no plain text secret
+func (r HTTPRequest) Validate() error {
+	if r.Method != "" && !httpMethods[r.Method] {
+		return errors.New("Request method is not supported")
+	}
+
+	for name, value := range r.Headers {
+		if name == "" || strings.ContainsAny(name, " :\t\r\n") {
+			return errors.New("Request header name is not valid")
+		}
+
+		if credentialHeaderRe.MatchString(name) && !secrets.IsPrefixedReference(value) {
+			return fmt.Errorf("Request header %s must be a secret reference like ${env:NAME} or Bearer ${secret:name}", name)
+		}
+	}
+
+	if r.BearerToken != "" && !secrets.IsReference(r.BearerToken) {
+		return errors.New("Bearer token must be a secret reference like ${env:NAME} or ${secret:name}")
+	}
+
+	if r.BasicAuthPassword != "" && !secrets.IsReference(r.BasicAuthPassword) {
+		return errors.New("Basic auth password must be a secret reference like ${env:NAME} or ${secret:name}")
+	}
+
+	return nil
+}
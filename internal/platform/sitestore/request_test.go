@@ -0,0 +1,73 @@
+package sitestore
+
+import "testing"
+
+func TestHTTPRequest_Validate(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		request HTTPRequest
+		hasErr  bool
+	}{
+		{
+			name:    "Validating a POST request",
+			request: HTTPRequest{Method: "POST", Headers: map[string]string{"Content-Type": "application/json"}, Body: "{}"},
+		},
+		{
+			name:    "Validating an unsupported method",
+			request: HTTPRequest{Method: "BREW"},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating an invalid header name",
+			request: HTTPRequest{Headers: map[string]string{"X Api Key": "${env:KEY}"}},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating a referenced authorization header",
+			request: HTTPRequest{Headers: map[string]string{"Authorization": "Bearer ${env:TOKEN}"}},
+		},
+		{
+			name:    "Validating a plain text authorization header",
+			request: HTTPRequest{Headers: map[string]string{"Authorization": "Bearer hunter2"}},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating a plain text cookie",
+			request: HTTPRequest{Headers: map[string]string{"Cookie": "sid=hunter2"}},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating a plain text API key header",
+			request: HTTPRequest{Headers: map[string]string{"X-Api-Key": "hunter2"}},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating a referenced bearer token",
+			request: HTTPRequest{BearerToken: "${env:TOKEN}"},
+		},
+		{
+			name:    "Validating a plain text bearer token",
+			request: HTTPRequest{BearerToken: "hunter2"},
+			hasErr:  true,
+		},
+		{
+			name:    "Validating a plain text basic auth password",
+			request: HTTPRequest{BasicAuthUser: "gohealth", BasicAuthPassword: "hunter2"},
+			hasErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.Validate()
+
+			if tc.hasErr && err == nil {
+				t.Errorf("Expected to return an error but got nil")
+			}
+
+			if !tc.hasErr && err != nil {
+				t.Errorf("Error is not expected. Got err: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package sitestore
+
+import "fmt"
+
+const (
+	// MaxRetryAttempts is the highest number of attempts a retry policy may ask for
+	MaxRetryAttempts = 10
+	// MaxRetryBackoff is the longest wait between two attempts, in milliseconds
+	MaxRetryBackoff = 5000
+)
+
+// defaultRetryClasses are the error classes retried when a retry policy does
+// not list any, i.e. the transient network failures
+var defaultRetryClasses = []string{ConnectError, TimeoutError}
+
+// RetryOptions represents how a failing check is retried within a single run.
+// A check is attempted up to Attempts times in total, waiting Backoff
+// milliseconds before the first retry and twice as long before every following
+// one, up to MaxRetryBackoff. Only failures of the ErrorClasses are retried,
+// connect and timeout errors when ErrorClasses is empty.
+type RetryOptions struct {
+	Attempts     int      `json:"attempts"`
+	Backoff      int      `json:"backoff,omitempty"`
+	ErrorClasses []string `json:"error_classes,omitempty"`
+}
+
+// Validate checks that the retry policy is bounded and only lists known error
+// classes
+func (o RetryOptions) Validate() error {
+	if o.Attempts < 1 || o.Attempts > MaxRetryAttempts {
+		return fmt.Errorf("Retry attempts must be between 1 and %d", MaxRetryAttempts)
+	}
+
+	if o.Backoff < 0 || o.Backoff > MaxRetryBackoff {
+		return fmt.Errorf("Retry backoff must be between 0 and %d milliseconds", MaxRetryBackoff)
+	}
+
+	for _, c := range o.ErrorClasses {
+		if !contains(retryableClasses, c) {
+			return fmt.Errorf("Retry error class %q is not valid", c)
+		}
+	}
+
+	return nil
+}
+
+// Retries tells whether a failure of the given error class is retried
+func (o RetryOptions) Retries(errClass string) bool {
+	if len(o.ErrorClasses) == 0 {
+		return contains(defaultRetryClasses, errClass)
+	}
+
+	return contains(o.ErrorClasses, errClass)
+}
+
+// retryableClasses are the error classes a retry policy may list
+var retryableClasses = []string{
+	DNSError, ConnectError, TLSError, TimeoutError, StatusMismatchError, AssertionError, UnknownError,
+}
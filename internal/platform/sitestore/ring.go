@@ -0,0 +1,30 @@
+package sitestore
+
+// ring is a fixed size buffer of check results that overwrites the oldest
+// result once it is full
+type ring struct {
+	results []CheckResult
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{results: make([]CheckResult, size)}
+}
+
+func (r *ring) push(res CheckResult) {
+	r.results[r.next] = res
+	r.next = (r.next + 1) % len(r.results)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// all returns the buffered results, oldest first
+func (r *ring) all() []CheckResult {
+	if !r.full {
+		return append([]CheckResult(nil), r.results[:r.next]...)
+	}
+
+	return append(append([]CheckResult(nil), r.results[r.next:]...), r.results[:r.next]...)
+}
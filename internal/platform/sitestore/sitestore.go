@@ -2,8 +2,10 @@ package sitestore
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,33 +17,273 @@ const (
 	Healthy
 	// Unhealthy indicate that the site is not healthy
 	Unhealthy
+	// Warning indicate that the site is healthy but needs attention soon
+	Warning
+	// Degraded indicate that the site responds, but slower than its warning latency
+	Degraded
+	// Cancelled indicate that the check was cancelled before it completed. It
+	// is only ever the status of a check result, sites keep their status.
+	Cancelled
+)
+
+// statusTexts are the names of the site statuses
+var statusTexts = map[int]string{
+	Unknown:   "unknown",
+	Healthy:   "healthy",
+	Unhealthy: "unhealthy",
+	Warning:   "warning",
+	Degraded:  "degraded",
+	Cancelled: "cancelled",
+}
+
+// StatusText returns the name of a site status
+func StatusText(status int) string {
+	if text, found := statusTexts[status]; found {
+		return text
+	}
+
+	return statusTexts[Unknown]
+}
+
+// StatusText returns the name of the site status
+func (st Site) StatusText() string {
+	return StatusText(st.Status)
+}
+
+const (
+	// HTTPCheck indicate that the site is checked with an HTTP request
+	HTTPCheck = "http"
+	// TCPCheck indicate that the site is checked by opening a TCP connection
+	TCPCheck = "tcp"
+	// TLSCheck indicate that the site is checked by inspecting its TLS certificate
+	TLSCheck = "tls"
+	// DNSCheck indicate that the site is checked by resolving its DNS records
+	DNSCheck = "dns"
+	// GRPCCheck indicate that the site is checked with the gRPC health checking protocol
+	GRPCCheck = "grpc"
+	// WebSocketCheck indicate that the site is checked with a WebSocket handshake
+	WebSocketCheck = "websocket"
+	// PostgresCheck indicate that the site is a Postgres database checked with SELECT 1
+	PostgresCheck = "postgres"
+	// MySQLCheck indicate that the site is a MySQL database checked with SELECT 1
+	MySQLCheck = "mysql"
+	// RedisCheck indicate that the site is a Redis server checked with PING
+	RedisCheck = "redis"
+	// SMTPCheck indicate that the site is a mail server checked with its SMTP banner
+	SMTPCheck = "smtp"
+	// IMAPCheck indicate that the site is a mail server checked with its IMAP greeting
+	IMAPCheck = "imap"
+)
+
+// checkTypes holds the registered check types along with the URL schemes
+// each accepts, an empty list accepting any absolute URL
+var checkTypes = struct {
+	schemes map[string][]string
+	sync.RWMutex
+}{schemes: map[string][]string{
+	HTTPCheck:      {"http", "https"},
+	TCPCheck:       {"tcp"},
+	TLSCheck:       {"tls"},
+	DNSCheck:       {"dns"},
+	GRPCCheck:      {"grpc", "grpcs"},
+	WebSocketCheck: {"ws", "wss"},
+	PostgresCheck:  {"postgres", "postgresql"},
+	MySQLCheck:     {"mysql"},
+	RedisCheck:     {"redis", "rediss"},
+	SMTPCheck:      {"smtp", "smtps"},
+	IMAPCheck:      {"imap", "imaps"},
+}}
+
+// RegisterCheckType makes a check type valid for sites, restricting their URL
+// to the given schemes. Registering a known check type without schemes keeps
+// the schemes it had.
+func RegisterCheckType(checkType string, schemes ...string) {
+	checkTypes.Lock()
+	defer checkTypes.Unlock()
+
+	if _, found := checkTypes.schemes[checkType]; found && len(schemes) == 0 {
+		return
+	}
+	checkTypes.schemes[checkType] = schemes
+}
+
+const (
+	// DNSError indicate that the site host could not be resolved
+	DNSError = "dns"
+	// ConnectError indicate that a connection to the site could not be established
+	ConnectError = "connect"
+	// TLSError indicate that the TLS handshake with the site failed
+	TLSError = "tls"
+	// TimeoutError indicate that the site did not respond within the timeout
+	TimeoutError = "timeout"
+	// StatusMismatchError indicate that the site responded with an unexpected status
+	StatusMismatchError = "status_mismatch"
+	// AssertionError indicate that the site response body failed an assertion
+	AssertionError = "assertion"
+	// LatencyError indicate that the site responded slower than a latency threshold
+	LatencyError = "latency"
+	// CancelledError indicate that the check was cancelled, e.g. on shutdown
+	CancelledError = "cancelled"
+	// LimitedError indicate that the host limits held the check back for longer
+	// than its timeout, so the site was not probed yet
+	LimitedError = "limited"
+	// UnknownError indicate that the check failed for any other reason
+	UnknownError = "unknown"
 )
 
 // Site represents Site data
 type Site struct {
-	ID        int       `json:"id"`
-	URL       string    `json:"url"`
-	Status    int       `json:"status"`
+	ID        int    `json:"id"`
+	URL       string `json:"url"`
+	CheckType string `json:"check_type"`
+	// Interval is the number of seconds between two checks, 0 meaning the checker default
+	Interval int `json:"interval"`
+	// WarningLatency leaves a successful but slower check degraded, in milliseconds
+	WarningLatency int `json:"warning_latency,omitempty"`
+	// CriticalLatency makes a successful but slower check unhealthy, in milliseconds
+	CriticalLatency int `json:"critical_latency,omitempty"`
+	// ExpectedStatus lists the accepted HTTP status codes, see ParseStatusCodes
+	ExpectedStatus string `json:"expected_status"`
+	// BodyAssertions must all hold for the site to be healthy
+	BodyAssertions []BodyAssertion `json:"body_assertions,omitempty"`
+	// Request customizes the HTTP check request, nil being a plain GET
+	Request *HTTPRequest `json:"request,omitempty"`
+	// TCP configures the exchange of TCP checks
+	TCP *TCPOptions `json:"tcp,omitempty"`
+	// TLS configures TLS checks, and turns on certificate inspection for HTTPS checks
+	TLS *TLSOptions `json:"tls,omitempty"`
+	// DNS configures the query of DNS checks
+	DNS *DNSOptions `json:"dns,omitempty"`
+	// GRPC configures gRPC checks
+	GRPC *GRPCOptions `json:"grpc,omitempty"`
+	// WebSocket configures the exchange of WebSocket checks
+	WebSocket *WebSocketOptions `json:"websocket,omitempty"`
+	// DataStore configures how Postgres, MySQL and Redis checks connect
+	DataStore *DataStoreOptions `json:"data_store,omitempty"`
+	// Mail configures SMTP and IMAP checks
+	Mail *MailOptions `json:"mail,omitempty"`
+	// Content turns on content change detection for HTTP checks
+	Content *ContentOptions `json:"content,omitempty"`
+	// ContentHash is the last content hash seen
+	ContentHash string `json:"content_hash,omitempty"`
+	// Retry retries transient failures within a check run, nil attempting every check once
+	Retry *RetryOptions `json:"retry,omitempty"`
+	// Status is debounced by FailThreshold and RecoverThreshold
+	Status int `json:"status"`
+	// RawStatus is the status of the last completed check
+	RawStatus int `json:"raw_status"`
+	// Flapping tells whether the raw status changes too often, see FlapWindow
+	Flapping bool `json:"flapping"`
+	// FailThreshold is the number of consecutive failures that make the site unhealthy
+	FailThreshold int `json:"fail_threshold,omitempty"`
+	// RecoverThreshold is the number of consecutive successes that make the site recover
+	RecoverThreshold int `json:"recover_threshold,omitempty"`
+	// ConsecutiveFailures is the number of failed checks in a row
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+	// ConsecutiveSuccesses is the number of successful checks in a row
+	ConsecutiveSuccesses int `json:"consecutive_successes,omitempty"`
+	// UpdatedAt is the time of the last completed check
 	UpdatedAt time.Time `json:"updated_at"`
+	// LastResult is the result of the last completed check
+	LastResult *CheckResult `json:"last_result,omitempty"`
+}
+
+// CheckResult represents the outcome of a single site health check
+type CheckResult struct {
+	Status     int           `json:"status"`
+	Latency    time.Duration `json:"latency"`
+	StatusCode int           `json:"status_code"`
+	BodySize   int64         `json:"body_size"`
+	ErrorClass string        `json:"error_class,omitempty"`
+	ErrorMsg   string        `json:"error_msg,omitempty"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Timings    *Timings      `json:"timings,omitempty"`
+
+	Certificate *Certificate `json:"certificate,omitempty"`
+	// ServingStatus is the status reported by the gRPC health service of gRPC checks
+	ServingStatus string `json:"serving_status,omitempty"`
+
+	// ContentHash is the hash of the watched content
+	ContentHash string `json:"content_hash,omitempty"`
+	// ContentChanged tells whether ContentHash differs from PreviousContentHash, the last hash seen
+	ContentChanged      bool   `json:"content_changed,omitempty"`
+	PreviousContentHash string `json:"previous_content_hash,omitempty"`
+
+	// Attempts lists every try of a check run under a retry policy, the last one being the result
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+// Attempt represents a single try of a check run under a retry policy
+type Attempt struct {
+	Status     int           `json:"status"`
+	Latency    time.Duration `json:"latency"`
+	ErrorClass string        `json:"error_class,omitempty"`
+	ErrorMsg   string        `json:"error_msg,omitempty"`
 }
 
-// Store represent data store for sites
-type Store struct {
+// Retries returns the number of times the check was retried before its result
+func (res CheckResult) Retries() int {
+	if len(res.Attempts) == 0 {
+		return 0
+	}
+
+	return len(res.Attempts) - 1
+}
+
+// Timings represents the per-phase durations of an HTTP check. FirstByte runs
+// from the request being written until the first response byte, i.e. the time
+// spent by the server.
+type Timings struct {
+	DNSLookup    time.Duration `json:"dns_lookup"`
+	Connect      time.Duration `json:"connect"`
+	TLSHandshake time.Duration `json:"tls_handshake"`
+	// Handshake is the upgrade handshake of WebSocket checks
+	Handshake time.Duration `json:"handshake,omitempty"`
+	FirstByte time.Duration `json:"first_byte"`
+	Transfer  time.Duration `json:"transfer"`
+}
+
+// HistorySize is the number of check results kept per site
+const HistorySize = 500
+
+// Store is the interface implemented by every site storage backend
+type Store interface {
+	List() []Site
+	ListFilter(lookbackPeriod int) []Site
+	Get(siteID int) (Site, error)
+	Add(st Site) error
+	UpdateHealth(siteID int, status int) error
+	UpdateResult(siteID int, res CheckResult) error
+	History(siteID int, from, to time.Time) ([]CheckResult, error)
+	Delete(siteID int) error
+	Revision() int64
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*BoltStore)(nil)
+)
+
+// MemoryStore represent an in-memory data store for sites
+type MemoryStore struct {
 	sites     map[int]*Site
+	history   map[int]*ring
 	idTracker int
+	revision  int64
 	sync.RWMutex
 }
 
-// NewStore construct a new Store
-func NewStore() Store {
-	return Store{
+// NewStore construct a new MemoryStore
+func NewStore() MemoryStore {
+	return MemoryStore{
 		sites:     make(map[int]*Site),
+		history:   make(map[int]*ring),
 		idTracker: 0,
 	}
 }
 
 // List returns a collection of sites
-func (str *Store) List() []Site {
+func (str *MemoryStore) List() []Site {
 	str.RLock()
 	defer str.RUnlock()
 
@@ -57,15 +299,27 @@ func (str *Store) List() []Site {
 	return sites
 }
 
+// Get returns a single site
+func (str *MemoryStore) Get(siteID int) (Site, error) {
+	str.RLock()
+	defer str.RUnlock()
+
+	s, found := str.sites[siteID]
+	if !found {
+		return Site{}, errors.New("Site does not exist")
+	}
+
+	return *s, nil
+}
+
 // ListFilter returns a collection of sites filtered by their last updated at in seconds
-func (str *Store) ListFilter(lookbackPeriod int) []Site {
+func (str *MemoryStore) ListFilter(lookbackPeriod int) []Site {
 	str.RLock()
 	defer str.RUnlock()
 
-	filter := time.Now().Add(time.Duration(-lookbackPeriod) * time.Second)
 	sites := make([]Site, 0)
 	for _, site := range str.sites {
-		if site.UpdatedAt.IsZero() || site.UpdatedAt.Before(filter) {
+		if isStale(*site, lookbackPeriod) {
 			sites = append(sites, *site)
 		}
 	}
@@ -78,15 +332,10 @@ func (str *Store) ListFilter(lookbackPeriod int) []Site {
 }
 
 // Add adds a single site to the store
-func (str *Store) Add(st Site) error {
-	// Validate URL
-	u, err := url.ParseRequestURI(st.URL)
+func (str *MemoryStore) Add(st Site) error {
+	st, err := validate(st)
 	if err != nil {
-		return errors.New("Site URL is not valid")
-	} else if u.Scheme == "" || u.Host == "" {
-		return errors.New("Site URL must be an absolute URL")
-	} else if u.Scheme != "http" && u.Scheme != "https" {
-		return errors.New("Site URL must begin with http or https")
+		return err
 	}
 
 	// Validate duplicate URL
@@ -104,6 +353,7 @@ func (str *Store) Add(st Site) error {
 			str.idTracker = str.idTracker + 1
 			st.ID = str.idTracker
 			str.sites[str.idTracker] = &st
+			str.revision++
 		}
 		str.Unlock()
 	}
@@ -112,7 +362,7 @@ func (str *Store) Add(st Site) error {
 }
 
 // UpdateHealth update the health status of a site
-func (str *Store) UpdateHealth(siteID int, status int) error {
+func (str *MemoryStore) UpdateHealth(siteID int, status int) error {
 	str.Lock()
 	defer str.Unlock()
 
@@ -127,8 +377,78 @@ func (str *Store) UpdateHealth(siteID int, status int) error {
 	return nil
 }
 
+// UpdateResult records the result of a health check on a site
+func (str *MemoryStore) UpdateResult(siteID int, res CheckResult) error {
+	str.Lock()
+	defer str.Unlock()
+
+	s, found := str.sites[siteID]
+
+	if !found {
+		return errors.New("Site does not exist")
+	}
+
+	if res.CheckedAt.IsZero() {
+		res.CheckedAt = time.Now()
+	}
+
+	applyResult(s, &res)
+
+	h, found := str.history[siteID]
+	if !found {
+		h = newRing(HistorySize)
+		str.history[siteID] = h
+	}
+	h.push(res)
+	s.Flapping = isFlapping(h.all(), res.CheckedAt)
+
+	return nil
+}
+
+// applyResult records a check result on its site. Cancelled checks tell
+// nothing about the site, they are only kept in its history.
+func applyResult(st *Site, res *CheckResult) {
+	if res.Status == Cancelled {
+		return
+	}
+
+	trackContent(st, res)
+	debounce(st, *res)
+	st.UpdatedAt = res.CheckedAt
+	st.LastResult = res
+}
+
+// History returns the check results of a site that were recorded between from
+// and to, oldest first. A zero from or to leaves that end of the window open.
+func (str *MemoryStore) History(siteID int, from, to time.Time) ([]CheckResult, error) {
+	str.RLock()
+	defer str.RUnlock()
+
+	if _, found := str.sites[siteID]; !found {
+		return nil, errors.New("Site does not exist")
+	}
+
+	results := make([]CheckResult, 0)
+	h, found := str.history[siteID]
+	if !found {
+		return results, nil
+	}
+
+	for _, res := range h.all() {
+		if !from.IsZero() && res.CheckedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && res.CheckedAt.After(to) {
+			continue
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
 // Delete deletes a site from the store
-func (str *Store) Delete(siteID int) error {
+func (str *MemoryStore) Delete(siteID int) error {
 	str.Lock()
 	defer str.Unlock()
 
@@ -137,5 +457,138 @@ func (str *Store) Delete(siteID int) error {
 	}
 
 	delete(str.sites, siteID)
+	delete(str.history, siteID)
+	str.revision++
 	return nil
 }
+
+// Revision returns a number that changes whenever a site is added or deleted
+func (str *MemoryStore) Revision() int64 {
+	str.RLock()
+	defer str.RUnlock()
+
+	return str.revision
+}
+
+// validate checks that a site can be stored and fills in its defaults
+func validate(st Site) (Site, error) {
+	if st.CheckType == "" {
+		st.CheckType = HTTPCheck
+	}
+
+	checkTypes.RLock()
+	schemes, found := checkTypes.schemes[st.CheckType]
+	checkTypes.RUnlock()
+	if !found {
+		return st, errors.New("Site check type is not supported")
+	}
+
+	// Validate URL
+	u, err := url.ParseRequestURI(st.URL)
+	if err != nil {
+		return st, errors.New("Site URL is not valid")
+	} else if u.Scheme == "" || u.Host == "" {
+		return st, errors.New("Site URL must be an absolute URL")
+	} else if len(schemes) > 0 && !contains(schemes, u.Scheme) {
+		return st, fmt.Errorf("Site URL must begin with %s", strings.Join(schemes, " or "))
+	}
+
+	if (st.CheckType == TCPCheck || st.CheckType == GRPCCheck) && u.Port() == "" {
+		return st, errors.New("Site URL must include a port")
+	}
+
+	if isDataStore(st.CheckType) {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return st, errors.New("Site URL must not hold a password, reference the DSN from secrets instead")
+		}
+	}
+
+	if st.Interval < 0 {
+		return st, errors.New("Site interval must not be negative")
+	}
+
+	if st.FailThreshold < 0 || st.RecoverThreshold < 0 {
+		return st, errors.New("Site failure and recovery thresholds must not be negative")
+	}
+
+	if st.WarningLatency < 0 || st.CriticalLatency < 0 {
+		return st, errors.New("Site latency thresholds must not be negative")
+	} else if st.WarningLatency > 0 && st.CriticalLatency > 0 && st.CriticalLatency <= st.WarningLatency {
+		return st, errors.New("Site critical latency must be above its warning latency")
+	}
+
+	if _, err := ParseStatusCodes(st.ExpectedStatus); err != nil {
+		return st, err
+	}
+
+	for _, a := range st.BodyAssertions {
+		if err := a.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.Request != nil {
+		if err := st.Request.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.TCP != nil {
+		if err := st.TCP.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.WebSocket != nil {
+		if err := st.WebSocket.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.DataStore != nil {
+		if err := st.DataStore.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.Content != nil {
+		if err := st.Content.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.TLS != nil && st.TLS.ExpiryWarningDays < 0 {
+		return st, errors.New("Certificate expiry warning days must not be negative")
+	}
+
+	if st.DNS != nil {
+		if err := st.DNS.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	if st.Retry != nil {
+		if err := st.Retry.Validate(); err != nil {
+			return st, err
+		}
+	}
+
+	return st, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStale reports whether a site was last updated more than lookbackPeriod
+// seconds ago or was never updated
+func isStale(st Site, lookbackPeriod int) bool {
+	filter := time.Now().Add(time.Duration(-lookbackPeriod) * time.Second)
+	return st.UpdatedAt.IsZero() || st.UpdatedAt.Before(filter)
+}
@@ -0,0 +1,101 @@
+package sitestore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// snapshot represents the JSON document a MemoryStore is saved as
+type snapshot struct {
+	IDTracker int                   `json:"id_tracker"`
+	Sites     []Site                `json:"sites"`
+	History   map[int][]CheckResult `json:"history"`
+}
+
+// Snapshot saves the content of the store to a JSON file. The file is written
+// to a temporary file first and renamed over path, so a crash never leaves a
+// partially written snapshot behind.
+func (str *MemoryStore) Snapshot(path string) error {
+	str.RLock()
+	snap := snapshot{
+		IDTracker: str.idTracker,
+		Sites:     make([]Site, 0, len(str.sites)),
+		History:   make(map[int][]CheckResult, len(str.history)),
+	}
+	for _, site := range str.sites {
+		snap.Sites = append(snap.Sites, *site)
+	}
+	for id, h := range str.history {
+		snap.History[id] = h.all()
+	}
+	str.RUnlock()
+
+	sort.Slice(snap.Sites, func(i, j int) bool {
+		return snap.Sites[i].ID < snap.Sites[j].ID
+	})
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Restore replaces the content of the store with the snapshot saved at path
+func (str *MemoryStore) Restore(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	sites := make(map[int]*Site, len(snap.Sites))
+	for i := range snap.Sites {
+		sites[snap.Sites[i].ID] = &snap.Sites[i]
+	}
+
+	history := make(map[int]*ring, len(snap.History))
+	for id, results := range snap.History {
+		h := newRing(HistorySize)
+		for _, res := range results {
+			h.push(res)
+		}
+		history[id] = h
+	}
+
+	str.Lock()
+	defer str.Unlock()
+
+	str.sites = sites
+	str.history = history
+	str.idTracker = snap.IDTracker
+	str.revision++
+	return nil
+}
@@ -0,0 +1,63 @@
+package sitestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gohealth.json")
+
+	str := NewStore()
+	str.Add(site1)
+	str.Add(site2)
+	str.Add(site3)
+	str.Delete(3)
+	str.UpdateResult(1, CheckResult{Status: Unhealthy, StatusCode: 503})
+	str.UpdateResult(1, CheckResult{Status: Healthy, StatusCode: 200})
+
+	if err := str.Snapshot(path); err != nil {
+		t.Fatalf("Error is not expected. Got err: %v", err)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected only the snapshot file to be left behind but got %v", files)
+	}
+
+	restored := NewStore()
+	if err := restored.Restore(path); err != nil {
+		t.Fatalf("Error is not expected. Got err: %v", err)
+	}
+
+	sites := restored.List()
+	if len(sites) != 2 {
+		t.Fatalf("Expected Sites length of %d, but it was %d instead.", 2, len(sites))
+	}
+
+	if s := sites[0]; s.Status != Healthy || s.LastResult == nil || s.LastResult.StatusCode != 200 {
+		t.Errorf("Expected the first site result to be restored but got %+v", s)
+	}
+
+	results, _ := restored.History(1, time.Time{}, time.Time{})
+	if len(results) != 2 || results[0].StatusCode != 503 {
+		t.Errorf("Expected the first site history to be restored but got %v", results)
+	}
+
+	restored.Add(site4)
+	if _, err := restored.Get(4); err != nil {
+		t.Errorf("Expected ID tracker to be restored but got %v", restored.List())
+	}
+}
+
+func TestRestore_MissingFile(t *testing.T) {
+	str := NewStore()
+	err := str.Restore(filepath.Join(t.TempDir(), "gohealth.json"))
+
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error but got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package sitestore
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DefaultExpectedStatus is used by sites that do not declare their accepted statuses
+const DefaultExpectedStatus = "200"
+
+// StatusCodes represents a set of accepted HTTP status codes
+type StatusCodes []statusRange
+
+type statusRange struct {
+	from int
+	to   int
+}
+
+// ParseStatusCodes parses a comma separated list of status codes and ranges,
+// e.g. `200-299,301`. An empty spec accepts DefaultExpectedStatus.
+func ParseStatusCodes(spec string) (StatusCodes, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultExpectedStatus
+	}
+
+	var codes StatusCodes
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := parseStatusCode(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		to := from
+		if len(bounds) == 2 {
+			if to, err = parseStatusCode(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		if from > to {
+			return nil, errors.New("Expected status range must be ascending")
+		}
+
+		codes = append(codes, statusRange{from: from, to: to})
+	}
+
+	return codes, nil
+}
+
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, errors.New("Expected status must be a list of status codes or ranges like 200-299,301")
+	}
+
+	return code, nil
+}
+
+// Match reports whether the status code is accepted
+func (codes StatusCodes) Match(code int) bool {
+	for _, r := range codes {
+		if code >= r.from && code <= r.to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchAny reports whether any status code between from and to is accepted
+func (codes StatusCodes) MatchAny(from, to int) bool {
+	for _, r := range codes {
+		if r.from <= to && r.to >= from {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+package sitestore
+
+import "testing"
+
+func TestParseStatusCodes(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		spec     string
+		accepted []int
+		rejected []int
+		hasErr   bool
+	}{
+		{
+			name:     "Parsing an empty spec",
+			spec:     "",
+			accepted: []int{200},
+			rejected: []int{204, 301, 500},
+		},
+		{
+			name:     "Parsing a list of codes",
+			spec:     "204, 301",
+			accepted: []int{204, 301},
+			rejected: []int{200, 302},
+		},
+		{
+			name:     "Parsing ranges and codes",
+			spec:     "200-299,301,401",
+			accepted: []int{200, 204, 299, 301, 401},
+			rejected: []int{300, 302, 403, 500},
+		},
+		{
+			name:   "Parsing a descending range",
+			spec:   "299-200",
+			hasErr: true,
+		},
+		{
+			name:   "Parsing a non numeric code",
+			spec:   "200,ok",
+			hasErr: true,
+		},
+		{
+			name:   "Parsing an out of range code",
+			spec:   "200-999",
+			hasErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			codes, err := ParseStatusCodes(tc.spec)
+
+			if tc.hasErr {
+				if err == nil {
+					t.Errorf("Expected to return an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Error is not expected. Got err: %v", err)
+			}
+
+			for _, code := range tc.accepted {
+				if !codes.Match(code) {
+					t.Errorf("Expected %d to be accepted", code)
+				}
+			}
+
+			for _, code := range tc.rejected {
+				if codes.Match(code) {
+					t.Errorf("Expected %d to be rejected", code)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusCodes_MatchAny(t *testing.T) {
+	codes, _ := ParseStatusCodes("200-299,401")
+
+	if codes.MatchAny(300, 399) {
+		t.Errorf("Expected no redirect status to be accepted")
+	}
+
+	if !codes.MatchAny(400, 499) {
+		t.Errorf("Expected a client error status to be accepted")
+	}
+}
@@ -0,0 +1,23 @@
+package sitestore
+
+import (
+	"errors"
+	"regexp"
+)
+
+// TCPOptions represents the exchange of a TCP check. Send is written once the
+// connection is open, then the reply must match the Expect regular expression.
+// Both are optional, a TCP check without options only opens the connection.
+type TCPOptions struct {
+	Send   string `json:"send,omitempty"`
+	Expect string `json:"expect,omitempty"`
+}
+
+// Validate checks that the expected reply is a valid regular expression
+func (o TCPOptions) Validate() error {
+	if _, err := regexp.Compile(o.Expect); err != nil {
+		return errors.New("TCP expected reply regex is not valid")
+	}
+
+	return nil
+}
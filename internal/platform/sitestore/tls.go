@@ -0,0 +1,22 @@
+package sitestore
+
+import "time"
+
+// DefaultExpiryWarningDays is the number of days before its certificate
+// expires a site gets a warning, unless its TLSOptions say otherwise
+const DefaultExpiryWarningDays = 14
+
+// TLSOptions represents how the certificate of a site is inspected
+type TLSOptions struct {
+	ExpiryWarningDays int `json:"expiry_warning_days,omitempty"`
+}
+
+// Certificate represents the inspected leaf certificate of a site
+type Certificate struct {
+	Subject          string    `json:"subject"`
+	Issuer           string    `json:"issuer"`
+	SANs             []string  `json:"sans"`
+	NotAfter         time.Time `json:"not_after"`
+	DaysUntilExpiry  int       `json:"days_until_expiry"`
+	HostnameMismatch bool      `json:"hostname_mismatch"`
+}
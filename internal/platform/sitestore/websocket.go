@@ -0,0 +1,24 @@
+package sitestore
+
+import (
+	"errors"
+	"regexp"
+)
+
+// WebSocketOptions represents the exchange of a WebSocket check. Send is sent
+// as a text message once the handshake is done, then a reply must match the
+// Expect regular expression, or echo Send when Expect is empty. A WebSocket
+// check without options only performs the handshake.
+type WebSocketOptions struct {
+	Send   string `json:"send,omitempty"`
+	Expect string `json:"expect,omitempty"`
+}
+
+// Validate checks that the expected reply is a valid regular expression
+func (o WebSocketOptions) Validate() error {
+	if _, err := regexp.Compile(o.Expect); err != nil {
+		return errors.New("WebSocket expected reply regex is not valid")
+	}
+
+	return nil
+}
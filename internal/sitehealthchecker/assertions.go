@@ -0,0 +1,53 @@
+package sitehealthchecker
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/levady/gohealth/internal/platform/jsonpath"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// maxAssertedBodySize is the number of body bytes the assertions are run against
+const maxAssertedBodySize = 1 << 20
+
+// assertBody returns an error describing the first assertion the body fails
+func assertBody(body []byte, assertions []sitestore.BodyAssertion) error {
+	for _, a := range assertions {
+		switch a.Type {
+		case sitestore.ContainsAssertion:
+			if !bytes.Contains(body, []byte(a.Value)) {
+				return fmt.Errorf("Body does not contain %q", a.Value)
+			}
+
+		case sitestore.NotContainsAssertion:
+			if bytes.Contains(body, []byte(a.Value)) {
+				return fmt.Errorf("Body contains %q", a.Value)
+			}
+
+		case sitestore.RegexAssertion:
+			re, err := regexp.Compile(a.Value)
+			if err != nil {
+				return err
+			}
+			if !re.Match(body) {
+				return fmt.Errorf("Body does not match %q", a.Value)
+			}
+
+		case sitestore.JSONPathAssertion:
+			e, err := jsonpath.Compile(a.Value)
+			if err != nil {
+				return err
+			}
+			if err := e.Match(body); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("Unknown body assertion type %q", a.Type)
+		}
+	}
+
+	return nil
+}
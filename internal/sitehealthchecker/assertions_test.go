@@ -0,0 +1,92 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestCheckHTTP_BodyAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status": "ok", "version": "1.2.0"}`))
+		default:
+			w.Write([]byte(`<html><body>Oops, something went wrong</body></html>`))
+		}
+	}))
+	defer ts.Close()
+
+	var testCases = []struct {
+		name       string
+		path       string
+		assertions []sitestore.BodyAssertion
+		expStatus  int
+		expMsg     string
+	}{
+		{
+			name:       "Body contains the expected text",
+			path:       "/",
+			assertions: []sitestore.BodyAssertion{{Type: sitestore.ContainsAssertion, Value: "<body>"}},
+			expStatus:  sitestore.Healthy,
+		},
+		{
+			name:       "Body contains an error page",
+			path:       "/",
+			assertions: []sitestore.BodyAssertion{{Type: sitestore.NotContainsAssertion, Value: "went wrong"}},
+			expStatus:  sitestore.Unhealthy,
+			expMsg:     `Body contains "went wrong"`,
+		},
+		{
+			name:       "Body matches a regex",
+			path:       "/health",
+			assertions: []sitestore.BodyAssertion{{Type: sitestore.RegexAssertion, Value: `"version": "1\.\d+\.\d+"`}},
+			expStatus:  sitestore.Healthy,
+		},
+		{
+			name: "JSON body satisfies every expression",
+			path: "/health",
+			assertions: []sitestore.BodyAssertion{
+				{Type: sitestore.JSONPathAssertion, Value: `$.status == "ok"`},
+				{Type: sitestore.JSONPathAssertion, Value: `$.version != "1.1.0"`},
+			},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "JSON body fails an expression",
+			path:       "/health",
+			assertions: []sitestore.BodyAssertion{{Type: sitestore.JSONPathAssertion, Value: `$.status == "down"`}},
+			expStatus:  sitestore.Unhealthy,
+			expMsg:     `$.status == "down": got "ok"`,
+		},
+		{
+			name:       "Error page is not JSON",
+			path:       "/",
+			assertions: []sitestore.BodyAssertion{{Type: sitestore.JSONPathAssertion, Value: `$.status == "ok"`}},
+			expStatus:  sitestore.Unhealthy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: ts.URL + tc.path, BodyAssertions: tc.assertions}
+			res := checkHTTP(context.Background(), site, 800*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if tc.expStatus == sitestore.Unhealthy && res.ErrorClass != sitestore.AssertionError {
+				t.Errorf("Expected an assertion error but got %v", res.ErrorClass)
+			}
+
+			if tc.expMsg != "" && res.ErrorMsg != tc.expMsg {
+				t.Errorf("Expected error message %v but got %v", tc.expMsg, res.ErrorMsg)
+			}
+		})
+	}
+}
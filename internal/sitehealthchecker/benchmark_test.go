@@ -5,49 +5,97 @@ package sitehealthchecker
 
 // go test -run none -bench . -benchtime 3s
 
-// Basic benchmark test.
+// Basic benchmark test. Sites are served by a local server that answers after
+// a short delay, standing in for the network round trip.
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/levady/gohealth/internal/platform/sitestore"
 )
 
-func BenchmarkParallelHealthChecks(b *testing.B) {
-	site1 := sitestore.Site{URL: "https://google.com"}
-	site2 := sitestore.Site{URL: "https://golang.org/doc/articles/wiki/#tmp_7"}
-	site3 := sitestore.Site{URL: "http://stat.us/200?sleep=40000"}
-	site4 := sitestore.Site{URL: "https://smartystreets.com/blog/2015/02/go-testing-part-1-vanillla"}
-	site5 := sitestore.Site{URL: "http://stat.us/200?sleep=10000"}
+// benchmarkSites adds n sites served by a local server to a new store
+func benchmarkSites(b *testing.B, n int) *sitestore.MemoryStore {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	b.Cleanup(ts.Close)
 
 	store := sitestore.NewStore()
-	store.Add(site1)
-	store.Add(site2)
-	store.Add(site3)
-	store.Add(site4)
-	store.Add(site5)
+	for i := 0; i < n; i++ {
+		store.Add(sitestore.Site{URL: fmt.Sprintf("%s/%d", ts.URL, i)})
+	}
 
+	return &store
+}
+
+func BenchmarkParallelHealthChecks(b *testing.B) {
+	store := benchmarkSites(b, 500)
+	pool := NewPool(store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		ParallelHealthChecks(&store, 800*time.Millisecond, 0)
+		ParallelHealthChecks(context.Background(), pool, 0)
 	}
 }
 
 func BenchmarkSerialHealthChecks(b *testing.B) {
-	site1 := sitestore.Site{URL: "https://google.com"}
-	site2 := sitestore.Site{URL: "https://golang.org/doc/articles/wiki/#tmp_7"}
-	site3 := sitestore.Site{URL: "http://stat.us/200?sleep=40000"}
-	site4 := sitestore.Site{URL: "https://smartystreets.com/blog/2015/02/go-testing-part-1-vanillla"}
-	site5 := sitestore.Site{URL: "http://stat.us/200?sleep=10000"}
+	store := benchmarkSites(b, 50)
 
-	store := sitestore.NewStore()
-	store.Add(site1)
-	store.Add(site2)
-	store.Add(site3)
-	store.Add(site4)
-	store.Add(site5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SerialHealthChecks(context.Background(), store, 800*time.Millisecond)
+	}
+}
+
+// BenchmarkGoroutinePerSite benchmarks the approach the pool replaced, a
+// goroutine per site throttled by a semaphore of one slot per CPU
+func BenchmarkGoroutinePerSite(b *testing.B) {
+	store := benchmarkSites(b, 500)
+	sites := store.List()
 
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		SerialHealthChecks(&store, 800*time.Millisecond)
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, runtime.NumCPU())
+
+		wg.Add(len(sites))
+		for _, s := range sites {
+			go func(s sitestore.Site) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				store.UpdateResult(s.ID, check(context.Background(), s, 800*time.Millisecond))
+				<-sem
+			}(s)
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	for _, workers := range []int{16, 64, 256} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			store := benchmarkSites(b, 500)
+			pool := NewPool(store, 800*time.Millisecond, workers, DefaultQueueSize)
+			defer pool.Close()
+			sites := store.List()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, s := range sites {
+					pool.Submit(context.Background(), s)
+				}
+				pool.Wait()
+			}
+		})
 	}
 }
@@ -0,0 +1,205 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/secrets"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// Checker is the interface implemented by every probe type. A check gives up
+// once timeout elapsed or ctx is done, whichever comes first.
+type Checker interface {
+	Check(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult
+}
+
+// CheckerFunc adapts an ordinary function to the Checker interface
+type CheckerFunc func(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult
+
+// Check calls f(ctx, site, timeout)
+func (f CheckerFunc) Check(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return f(ctx, site, timeout)
+}
+
+// registry holds the checkers keyed by their check type
+var registry = struct {
+	checkers map[string]Checker
+	sync.RWMutex
+}{
+	checkers: make(map[string]Checker),
+}
+
+// secretResolver resolves the secrets referenced by site configurations
+var secretResolver = secrets.NewResolver()
+
+// UseSecrets sets the resolver of the secrets referenced by site
+// configurations. It must be called before any check runs.
+func UseSecrets(r *secrets.Resolver) {
+	secretResolver = r
+}
+
+// hostLimiter limits the checks run against every host, nil leaving them unlimited
+var hostLimiter *HostLimiter
+
+// UseHostLimiter sets the limiter of the checks run against every host. It
+// must be called before any check runs.
+func UseHostLimiter(l *HostLimiter) {
+	hostLimiter = l
+}
+
+// Register makes a checker available for the given check type, and the check
+// type valid for sites. Registering the same check type twice replaces the
+// previous checker.
+func Register(checkType string, checker Checker) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.checkers[checkType] = checker
+	sitestore.RegisterCheckType(checkType)
+}
+
+// Lookup returns the checker registered for the given check type
+func Lookup(checkType string) (Checker, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	if checkType == "" {
+		checkType = sitestore.HTTPCheck
+	}
+
+	checker, found := registry.checkers[checkType]
+	if !found {
+		return nil, errors.New("Check type is not registered")
+	}
+
+	return checker, nil
+}
+
+// check dispatches a site to the checker registered for its check type,
+// retrying it under the site retry policy, then applies the site latency
+// thresholds to the result. A check that ctx cancelled is reported as
+// cancelled, whatever the checker made of it.
+func check(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	if ctx.Err() != nil {
+		return cancelled(sitestore.CheckResult{})
+	}
+
+	checker, err := Lookup(site.CheckType)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	res := retry(ctx, site, checker, timeout)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return cancelled(res)
+	}
+
+	return applyLatencyThresholds(site, res)
+}
+
+// cancelled marks a check result as cancelled, keeping what was measured
+func cancelled(res sitestore.CheckResult) sitestore.CheckResult {
+	res.Status = sitestore.Cancelled
+	res.ErrorClass = sitestore.CancelledError
+	res.ErrorMsg = "Check was cancelled before it completed"
+	return res
+}
+
+// applyLatencyThresholds makes a result that is otherwise fine unhealthy when
+// slower than the site critical latency, or degraded when slower than its
+// warning latency. Failed results are left alone.
+func applyLatencyThresholds(site sitestore.Site, res sitestore.CheckResult) sitestore.CheckResult {
+	if res.Status == sitestore.Unhealthy || res.Status == sitestore.Unknown || res.Status == sitestore.Cancelled {
+		return res
+	}
+
+	critical := time.Duration(site.CriticalLatency) * time.Millisecond
+	warning := time.Duration(site.WarningLatency) * time.Millisecond
+
+	switch {
+	case critical > 0 && res.Latency > critical:
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.LatencyError
+		res.ErrorMsg = fmt.Sprintf("Latency %s is above the critical threshold of %s", res.Latency.Round(time.Millisecond), critical)
+	case warning > 0 && res.Latency > warning && res.Status == sitestore.Healthy:
+		res.Status = sitestore.Degraded
+		res.ErrorClass = sitestore.LatencyError
+		res.ErrorMsg = fmt.Sprintf("Latency %s is above the warning threshold of %s", res.Latency.Round(time.Millisecond), warning)
+	}
+
+	return res
+}
+
+// failure builds an unhealthy check result for the given error
+func failure(errClass string, err error) sitestore.CheckResult {
+	return sitestore.CheckResult{
+		Status:     sitestore.Unhealthy,
+		ErrorClass: errClass,
+		ErrorMsg:   err.Error(),
+	}
+}
+
+// classifyError maps a probe error to one of the sitestore error classes
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return sitestore.DNSError
+	}
+
+	var (
+		recordErr tls.RecordHeaderError
+		alertErr  tls.AlertError
+		verifyErr *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+		certErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownCA) || errors.As(err, &hostErr) || errors.As(err, &certErr) {
+		return sitestore.TLSError
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return sitestore.TimeoutError
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return sitestore.ConnectError
+	}
+
+	return sitestore.UnknownError
+}
+
+// dialTCP connects to addr within timeout. The connection is closed as soon as
+// ctx is done, so that a cancelled check does not wait on its reads and writes.
+func dialTCP(ctx context.Context, addr string, timeout time.Duration) (net.Conn, error) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	return &ctxConn{Conn: conn, stop: stop}, nil
+}
+
+// ctxConn is a connection closed along with a context
+type ctxConn struct {
+	net.Conn
+	stop func() bool
+}
+
+// Close stops watching the context and closes the connection
+func (c *ctxConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
@@ -0,0 +1,202 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestLookup(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		checkType string
+		hasErr    bool
+	}{
+		{
+			name:      "Looking up the http checker",
+			checkType: sitestore.HTTPCheck,
+			hasErr:    false,
+		},
+		{
+			name:      "Looking up an empty check type",
+			checkType: "",
+			hasErr:    false,
+		},
+		{
+			name:      "Looking up an unregistered check type",
+			checkType: "carrier-pigeon",
+			hasErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := Lookup(tc.checkType)
+
+			if tc.hasErr && err == nil {
+				t.Errorf("Expected to return an error but got nil")
+			}
+
+			if !tc.hasErr && checker == nil {
+				t.Errorf("Expected to return a checker but got nil. Err: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "fake")
+		registry.Unlock()
+	}()
+
+	checked := make(chan string, 1)
+	Register("fake", CheckerFunc(func(_ context.Context, s sitestore.Site, _ time.Duration) sitestore.CheckResult {
+		checked <- s.URL
+		return sitestore.CheckResult{Status: sitestore.Healthy}
+	}))
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "fake"})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	ParallelHealthChecks(context.Background(), pool, 0)
+
+	if url := <-checked; url != "https://zempag.com" {
+		t.Errorf("Expected fake checker to check zempag.com but got %v", url)
+	}
+
+	if s := store.List()[0]; s.Status != sitestore.Healthy {
+		t.Errorf("Expected site to be healthy but got %v", s.Status)
+	}
+}
+
+func TestParallelHealthChecks_UnknownCheckType(t *testing.T) {
+	// Mocking, a check type sites may use but that no checker serves
+	sitestore.RegisterCheckType("carrier-pigeon")
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "carrier-pigeon"})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	ParallelHealthChecks(context.Background(), pool, 0)
+
+	if s := store.List()[0]; s.Status != sitestore.Unhealthy {
+		t.Errorf("Expected site with unknown check type to be unhealthy but got %v", s.Status)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	var testCases = []struct {
+		name string
+		err  error
+		exp  string
+	}{
+		{
+			name: "DNS lookup failure",
+			err:  &url.Error{Op: "Get", URL: "https://zempag.com", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "zempag.com"}}},
+			exp:  sitestore.DNSError,
+		},
+		{
+			name: "Connection refused",
+			err:  &url.Error{Op: "Get", URL: "https://zempag.com", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			exp:  sitestore.ConnectError,
+		},
+		{
+			name: "Deadline exceeded",
+			err:  &url.Error{Op: "Get", URL: "https://zempag.com", Err: context.DeadlineExceeded},
+			exp:  sitestore.TimeoutError,
+		},
+		{
+			name: "Anything else",
+			err:  errors.New("Timeout"),
+			exp:  sitestore.UnknownError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if class := classifyError(tc.err); class != tc.exp {
+				t.Errorf("Expected error class %v but got %v", tc.exp, class)
+			}
+		})
+	}
+}
+
+func TestApplyLatencyThresholds(t *testing.T) {
+	site := sitestore.Site{URL: "https://zempag.com", WarningLatency: 200, CriticalLatency: 1000}
+
+	var testCases = []struct {
+		name       string
+		site       sitestore.Site
+		input      sitestore.CheckResult
+		expStatus  int
+		expErrType string
+	}{
+		{
+			name:      "Responding within the warning latency",
+			site:      site,
+			input:     sitestore.CheckResult{Status: sitestore.Healthy, Latency: 150 * time.Millisecond},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Responding slower than the warning latency",
+			site:       site,
+			input:      sitestore.CheckResult{Status: sitestore.Healthy, Latency: 450 * time.Millisecond},
+			expStatus:  sitestore.Degraded,
+			expErrType: sitestore.LatencyError,
+		},
+		{
+			name:       "Responding slower than the critical latency",
+			site:       site,
+			input:      sitestore.CheckResult{Status: sitestore.Healthy, Latency: 1200 * time.Millisecond},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.LatencyError,
+		},
+		{
+			name:       "Failing slowly",
+			site:       site,
+			input:      sitestore.CheckResult{Status: sitestore.Unhealthy, Latency: 1200 * time.Millisecond, ErrorClass: sitestore.TimeoutError},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.TimeoutError,
+		},
+		{
+			name:       "Responding slowly with a certificate warning",
+			site:       site,
+			input:      sitestore.CheckResult{Status: sitestore.Warning, Latency: 450 * time.Millisecond, ErrorClass: sitestore.TLSError},
+			expStatus:  sitestore.Warning,
+			expErrType: sitestore.TLSError,
+		},
+		{
+			name:      "Responding slowly without thresholds",
+			site:      sitestore.Site{URL: "https://zempag.com"},
+			input:     sitestore.CheckResult{Status: sitestore.Healthy, Latency: 5 * time.Second},
+			expStatus: sitestore.Healthy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := applyLatencyThresholds(tc.site, tc.input)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q", tc.expErrType, res.ErrorClass)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package sitehealthchecker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// hashContent returns the SHA-256 hex digest of the watched content of body
+func hashContent(body []byte, opts sitestore.ContentOptions) (string, error) {
+	if opts.Extract != "" {
+		re, err := regexp.Compile(opts.Extract)
+		if err != nil {
+			return "", err
+		}
+
+		m := re.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("Body does not match content extraction %q", opts.Extract)
+		}
+
+		body = m[0]
+		if len(m) > 1 {
+			body = m[1]
+		}
+	}
+
+	for _, s := range opts.Strip {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return "", err
+		}
+		body = re.ReplaceAll(body, nil)
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,102 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestHashContent(t *testing.T) {
+	page := `<html><p>Rendered at 2026-10-17 09:41</p><main>Welcome to Zempag</main><input name="csrf" value="f3a9"></html>`
+
+	var testCases = []struct {
+		name    string
+		body    string
+		opts    sitestore.ContentOptions
+		expSame bool
+		hasErr  bool
+	}{
+		{
+			name: "Hashing a changed page",
+			body: strings.Replace(page, "Zempag", "Hacked", 1),
+		},
+		{
+			name:    "Hashing a page whose volatile sections changed",
+			body:    strings.NewReplacer("09:41", "10:02", "f3a9", "77c1").Replace(page),
+			opts:    sitestore.ContentOptions{Strip: []string{`\d{4}-\d{2}-\d{2} \d{2}:\d{2}`, `value="\w+"`}},
+			expSame: true,
+		},
+		{
+			name:    "Hashing an extracted section that did not change",
+			body:    strings.Replace(page, "09:41", "10:02", 1),
+			opts:    sitestore.ContentOptions{Extract: `<main>(.*)</main>`},
+			expSame: true,
+		},
+		{
+			name: "Hashing an extracted section that changed",
+			body: strings.Replace(page, "Zempag", "Hacked", 1),
+			opts: sitestore.ContentOptions{Extract: `<main>(.*)</main>`},
+		},
+		{
+			name:   "Hashing a page missing the extracted section",
+			body:   "<html>Maintenance</html>",
+			opts:   sitestore.ContentOptions{Extract: `<main>(.*)</main>`},
+			hasErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before, _ := hashContent([]byte(page), tc.opts)
+			after, err := hashContent([]byte(tc.body), tc.opts)
+
+			if tc.hasErr {
+				if err == nil {
+					t.Errorf("Expected to return an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Error is not expected. Got err: %v", err)
+			}
+
+			if (before == after) != tc.expSame {
+				t.Errorf("Expected hashes to be the same: %v but got %s and %s", tc.expSame, before, after)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP_Content(t *testing.T) {
+	// Mocking
+	implementedSiteChecker := siteChecker
+	defer func() {
+		siteChecker = implementedSiteChecker
+	}()
+
+	siteChecker = func(req *http.Request, _ time.Duration, _ bool) (*http.Response, error) {
+		switch req.URL.String() {
+		case "https://zempag.com":
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		default:
+			return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+		}
+	}
+
+	opts := &sitestore.ContentOptions{}
+
+	res := checkHTTP(context.Background(), sitestore.Site{URL: "https://zempag.com", Content: opts}, 800*time.Millisecond)
+	if res.Status != sitestore.Healthy || len(res.ContentHash) != 64 {
+		t.Errorf("Expected a healthy result with a SHA-256 content hash but got %+v", res)
+	}
+
+	res = checkHTTP(context.Background(), sitestore.Site{URL: "https://down.zempag.com", Content: opts}, 800*time.Millisecond)
+	if res.ContentHash != "" {
+		t.Errorf("Expected error pages not to be hashed but got %q", res.ContentHash)
+	}
+}
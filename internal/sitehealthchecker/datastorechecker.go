@@ -0,0 +1,111 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	Register(sitestore.PostgresCheck, CheckerFunc(checkPostgres))
+	Register(sitestore.MySQLCheck, CheckerFunc(checkMySQL))
+	Register(sitestore.RedisCheck, CheckerFunc(checkRedis))
+}
+
+func checkPostgres(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return checkDataStore(ctx, site, timeout, func(ctx context.Context, dsn string) error {
+		return selectOne(ctx, "postgres", dsn)
+	})
+}
+
+func checkMySQL(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return checkDataStore(ctx, site, timeout, func(ctx context.Context, dsn string) error {
+		dsn, err := mysqlDSN(dsn)
+		if err != nil {
+			return err
+		}
+		return selectOne(ctx, "mysql", dsn)
+	})
+}
+
+func checkRedis(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return checkDataStore(ctx, site, timeout, func(ctx context.Context, dsn string) error {
+		opts, err := redis.ParseURL(dsn)
+		if err != nil {
+			return err
+		}
+		opts.MaxRetries = -1
+
+		client := redis.NewClient(opts)
+		defer client.Close()
+
+		return client.Ping(ctx).Err()
+	})
+}
+
+// checkDataStore resolves the site DSN and runs ping with it within timeout
+func checkDataStore(ctx context.Context, site sitestore.Site, timeout time.Duration, ping func(ctx context.Context, dsn string) error) sitestore.CheckResult {
+	dsn := site.URL
+	if site.DataStore != nil && site.DataStore.DSN != "" {
+		var err error
+		if dsn, err = secretResolver.Expand(site.DataStore.DSN); err != nil {
+			return failure(sitestore.UnknownError, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx, dsn)
+	latency := time.Since(start)
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = latency
+		return res
+	}
+
+	return sitestore.CheckResult{Status: sitestore.Healthy, Latency: latency}
+}
+
+// selectOne runs SELECT 1 on a single connection to the database at dsn
+func selectOne(ctx context.Context, driver, dsn string) error {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var one int
+	return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// mysqlDSN converts a mysql:// URL to the DSN format of the MySQL driver.
+// Other DSNs are assumed to already be in that format.
+func mysqlDSN(dsn string) (string, error) {
+	if !strings.HasPrefix(dsn, "mysql://") {
+		return dsn, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = u.Host
+	cfg.User = u.User.Username()
+	cfg.Passwd, _ = u.User.Password()
+	cfg.DBName = strings.TrimPrefix(u.Path, "/")
+
+	return cfg.FormatDSN(), nil
+}
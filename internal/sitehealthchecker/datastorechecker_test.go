@@ -0,0 +1,216 @@
+package sitehealthchecker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// servePostgres answers SELECT 1 like a Postgres server trusting every client,
+// or rejects every client when denied
+func servePostgres(t *testing.T, denied bool) string {
+	addr := serveTCP(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+
+		// Startup, refusing SSL until the client sends its startup message
+		for {
+			var hdr [8]byte
+			if _, err := io.ReadFull(r, hdr[:]); err != nil {
+				return
+			}
+			rest := make([]byte, binary.BigEndian.Uint32(hdr[:4])-8)
+			io.ReadFull(r, rest)
+			if binary.BigEndian.Uint32(hdr[4:]) != 80877103 {
+				break
+			}
+			conn.Write([]byte("N"))
+		}
+
+		if denied {
+			conn.Write(pgMessage('E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00")))
+			return
+		}
+		conn.Write(pgMessage('R', []byte{0, 0, 0, 0}))
+		conn.Write(pgMessage('Z', []byte("I")))
+
+		for {
+			var hdr [5]byte
+			if _, err := io.ReadFull(r, hdr[:]); err != nil {
+				return
+			}
+			body := make([]byte, binary.BigEndian.Uint32(hdr[1:])-4)
+			io.ReadFull(r, body)
+			if hdr[0] != 'Q' {
+				return
+			}
+
+			// One int4 column named ?column? holding 1
+			field := append([]byte("?column?\x00"), 0, 0, 0, 0, 0, 0, 0, 0, 0, 23, 0, 4, 0xff, 0xff, 0xff, 0xff, 0, 0)
+			conn.Write(pgMessage('T', append([]byte{0, 1}, field...)))
+			conn.Write(pgMessage('D', []byte{0, 1, 0, 0, 0, 1, '1'}))
+			conn.Write(pgMessage('C', []byte("SELECT 1\x00")))
+			conn.Write(pgMessage('Z', []byte("I")))
+		}
+	})
+
+	return strings.Replace(addr, "tcp://", "postgres://", 1) + "/gohealth?sslmode=disable"
+}
+
+func pgMessage(typ byte, body []byte) []byte {
+	msg := []byte{typ, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(body)+4))
+	return append(msg, body...)
+}
+
+// serveMySQL answers SELECT 1 like a MySQL server accepting every client, or
+// rejects every client when denied
+func serveMySQL(t *testing.T, denied bool) string {
+	addr := serveTCP(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+
+		// Protocol 10 handshake offering mysql_native_password
+		var caps uint32 = 0x1 | 0x200 | 0x2000 | 0x8000 | 0x80000
+		hs := []byte("\x0a5.7.0-gohealth\x00")
+		hs = append(hs, 1, 0, 0, 0)
+		hs = append(hs, "abcdefgh\x00"...)
+		hs = append(hs, byte(caps), byte(caps>>8), 33, 2, 0, byte(caps>>16), byte(caps>>24), 21)
+		hs = append(hs, make([]byte, 10)...)
+		hs = append(hs, "ijklmnopqrst\x00mysql_native_password\x00"...)
+		conn.Write(mysqlPacket(0, hs))
+
+		if _, _, err := readMySQLPacket(r); err != nil {
+			return
+		}
+		if denied {
+			conn.Write(mysqlPacket(2, []byte("\xff\x15\x04#28000Access denied for user 'gohealth'")))
+			return
+		}
+		conn.Write(mysqlPacket(2, []byte{0, 0, 0, 2, 0, 0, 0}))
+
+		for {
+			_, cmd, err := readMySQLPacket(r)
+			if err != nil || cmd[0] != 0x03 {
+				return
+			}
+
+			// One BIGINT column named 1 holding 1
+			column := []byte("\x03def\x00\x00\x00\x011\x00\x0c\x3f\x00\x01\x00\x00\x00\x08\x81\x00\x00\x00\x00")
+			eof := []byte{0xfe, 0, 0, 2, 0}
+			conn.Write(mysqlPacket(1, []byte{1}))
+			conn.Write(mysqlPacket(2, column))
+			conn.Write(mysqlPacket(3, eof))
+			conn.Write(mysqlPacket(4, []byte{1, '1'}))
+			conn.Write(mysqlPacket(5, eof))
+		}
+	})
+
+	return strings.Replace(addr, "tcp://", "mysql://gohealth@", 1) + "/gohealth"
+}
+
+func mysqlPacket(seq byte, payload []byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func readMySQLPacket(r io.Reader) (byte, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, int(hdr[0])|int(hdr[1])<<8|int(hdr[2])<<16)
+	_, err := io.ReadFull(r, payload)
+	return hdr[3], payload, err
+}
+
+func TestCheckDataStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	secured := miniredis.RunT(t)
+	secured.RequireAuth("s3cr3t")
+
+	os.Setenv("GOHEALTH_TEST_REDIS_DSN", "redis://:s3cr3t@"+secured.Addr())
+	defer os.Unsetenv("GOHEALTH_TEST_REDIS_DSN")
+
+	// Grab a free port and release it so that nothing listens on it
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+
+	var testCases = []struct {
+		name       string
+		site       sitestore.Site
+		expStatus  int
+		expErrType string
+	}{
+		{
+			name:      "Selecting 1 from Postgres",
+			site:      sitestore.Site{URL: servePostgres(t, false), CheckType: sitestore.PostgresCheck},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Selecting 1 from Postgres with bad credentials",
+			site:       sitestore.Site{URL: servePostgres(t, true), CheckType: sitestore.PostgresCheck},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.UnknownError,
+		},
+		{
+			name:      "Selecting 1 from MySQL",
+			site:      sitestore.Site{URL: serveMySQL(t, false), CheckType: sitestore.MySQLCheck},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Selecting 1 from MySQL with bad credentials",
+			site:       sitestore.Site{URL: serveMySQL(t, true), CheckType: sitestore.MySQLCheck},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.UnknownError,
+		},
+		{
+			name:      "Pinging Redis",
+			site:      sitestore.Site{URL: "redis://" + mr.Addr(), CheckType: sitestore.RedisCheck},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name: "Pinging Redis with a DSN from secrets",
+			site: sitestore.Site{
+				URL:       "redis://" + secured.Addr(),
+				CheckType: sitestore.RedisCheck,
+				DataStore: &sitestore.DataStoreOptions{DSN: "${env:GOHEALTH_TEST_REDIS_DSN}"},
+			},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Pinging Redis without its password",
+			site:       sitestore.Site{URL: "redis://" + secured.Addr(), CheckType: sitestore.RedisCheck},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.UnknownError,
+		},
+		{
+			name:       "Pinging Redis on a closed port",
+			site:       sitestore.Site{URL: "redis://" + closed, CheckType: sitestore.RedisCheck},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.ConnectError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := check(context.Background(), tc.site, time.Second)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrType, res.ErrorClass, res.ErrorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func init() {
+	Register(sitestore.DNSCheck, CheckerFunc(checkDNS))
+}
+
+func checkDNS(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	u, err := url.Parse(site.URL)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	var opts sitestore.DNSOptions
+	if site.DNS != nil {
+		opts = *site.DNS
+	}
+	if opts.RecordType == "" {
+		opts.RecordType = sitestore.ARecord
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookup(ctx, newResolver(opts.Resolver), opts.RecordType, u.Hostname())
+	lookupDuration := time.Since(start)
+
+	res := sitestore.CheckResult{Status: sitestore.Healthy}
+	if err != nil {
+		res = failure(classifyError(err), err)
+	} else if missing := missingAnswers(opts.RecordType, answers, opts.Expect); len(missing) > 0 {
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.AssertionError
+		res.ErrorMsg = fmt.Sprintf("Expected %s records to include %s but got %s",
+			opts.RecordType, strings.Join(missing, ", "), strings.Join(answers, ", "))
+	}
+
+	res.Latency = lookupDuration
+	res.Timings = &sitestore.Timings{DNSLookup: lookupDuration}
+	return res
+}
+
+// newResolver returns a resolver querying the DNS server at addr, or the
+// system resolver when addr is empty
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// lookup resolves the recordType records of name. MX records are answered
+// with their host only.
+func lookup(ctx context.Context, r *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+	switch recordType {
+	case sitestore.ARecord, sitestore.AAAARecord:
+		network := "ip4"
+		if recordType == sitestore.AAAARecord {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case sitestore.CNAMERecord:
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case sitestore.MXRecord:
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, mx.Host)
+		}
+	case sitestore.TXTRecord:
+		return r.LookupTXT(ctx, name)
+	default:
+		return nil, fmt.Errorf("DNS record type %s is not supported", recordType)
+	}
+
+	for i, a := range answers {
+		answers[i] = normalizeAnswer(recordType, a)
+	}
+
+	return answers, nil
+}
+
+// missingAnswers returns the expected answers that were not resolved
+func missingAnswers(recordType string, answers, expect []string) []string {
+	var missing []string
+	for _, exp := range expect {
+		found := false
+		for _, a := range answers {
+			if a == normalizeAnswer(recordType, exp) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, exp)
+		}
+	}
+
+	return missing
+}
+
+// normalizeAnswer puts an answer in the form lookup returns, so that an
+// expected `mx.zempag.com` matches the resolved `mx.zempag.com.`
+func normalizeAnswer(recordType, a string) string {
+	switch recordType {
+	case sitestore.TXTRecord:
+		return a
+	case sitestore.ARecord, sitestore.AAAARecord:
+		if ip := net.ParseIP(strings.TrimSpace(a)); ip != nil {
+			return ip.String()
+		}
+	}
+
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(a)), ".")
+}
@@ -0,0 +1,141 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+	"github.com/miekg/dns"
+)
+
+// serveDNS answers queries from the records of zone on a local UDP port. Like
+// a real server it answers CNAME records to queries of any type.
+func serveDNS(t *testing.T, zone ...string) string {
+	var records []dns.RR
+	for _, z := range zone {
+		rr, err := dns.NewRR(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		q := req.Question[0]
+		for _, rr := range records {
+			h := rr.Header()
+			if h.Name == q.Name && (h.Rrtype == q.Qtype || h.Rrtype == dns.TypeCNAME) {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		known := false
+		for _, rr := range records {
+			known = known || rr.Header().Name == q.Name
+		}
+		if !known {
+			m.Rcode = dns.RcodeNameError
+		}
+
+		w.WriteMsg(m)
+	})
+
+	srv := &dns.Server{PacketConn: pc, Handler: handler}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestCheckDNS(t *testing.T) {
+	resolver := serveDNS(t,
+		"zempag.test. 60 IN A 203.0.113.10",
+		"zempag.test. 60 IN A 203.0.113.11",
+		"zempag.test. 60 IN AAAA 2001:db8::10",
+		"zempag.test. 60 IN MX 10 mx.zempag.test.",
+		`zempag.test. 60 IN TXT "v=spf1 -all"`,
+		"www.zempag.test. 60 IN CNAME zempag.test.",
+	)
+
+	var testCases = []struct {
+		name       string
+		url        string
+		opts       sitestore.DNSOptions
+		expStatus  int
+		expErrType string
+	}{
+		{
+			name:      "Resolving A records",
+			url:       "dns://zempag.test",
+			opts:      sitestore.DNSOptions{Expect: []string{"203.0.113.11"}},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Resolving AAAA records",
+			url:       "dns://zempag.test",
+			opts:      sitestore.DNSOptions{RecordType: sitestore.AAAARecord, Expect: []string{"2001:0db8::0010"}},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Resolving a CNAME record",
+			url:       "dns://www.zempag.test",
+			opts:      sitestore.DNSOptions{RecordType: sitestore.CNAMERecord, Expect: []string{"zempag.test"}},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Resolving MX records",
+			url:       "dns://zempag.test",
+			opts:      sitestore.DNSOptions{RecordType: sitestore.MXRecord, Expect: []string{"MX.zempag.test."}},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Resolving TXT records",
+			url:       "dns://zempag.test",
+			opts:      sitestore.DNSOptions{RecordType: sitestore.TXTRecord, Expect: []string{"v=spf1 -all"}},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Resolving an unexpected answer",
+			url:        "dns://zempag.test",
+			opts:       sitestore.DNSOptions{Expect: []string{"203.0.113.12"}},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.AssertionError,
+		},
+		{
+			name:       "Resolving an unknown name",
+			url:        "dns://nowhere.zempag.test",
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.DNSError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.Resolver = resolver
+			site := sitestore.Site{URL: tc.url, CheckType: sitestore.DNSCheck, DNS: &opts}
+			res := checkDNS(context.Background(), site, time.Second)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrType, res.ErrorClass, res.ErrorMsg)
+			}
+
+			if res.Timings == nil || res.Timings.DNSLookup <= 0 || res.Latency != res.Timings.DNSLookup {
+				t.Errorf("Expected resolution latency to be recorded but got %+v", res.Timings)
+			}
+		})
+	}
+}
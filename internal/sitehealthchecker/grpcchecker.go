@@ -0,0 +1,94 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	Register(sitestore.GRPCCheck, CheckerFunc(checkGRPC))
+}
+
+// checkGRPC calls grpc.health.v1.Health/Check on the site, in plaintext for
+// grpc:// URLs and over TLS for grpcs:// URLs
+func checkGRPC(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	u, err := url.Parse(site.URL)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	creds := insecure.NewCredentials()
+	if u.Scheme == "grpcs" {
+		creds = credentials.NewTLS(&tls.Config{ServerName: u.Hostname(), RootCAs: rootCAs})
+	}
+
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+	defer conn.Close()
+
+	var service string
+	if site.GRPC != nil {
+		service = site.GRPC.Service
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	latency := time.Since(start)
+	if err != nil {
+		res := failure(classifyGRPCError(err), err)
+		res.Latency = latency
+		return res
+	}
+
+	res := sitestore.CheckResult{
+		Status:        sitestore.Healthy,
+		Latency:       latency,
+		ServingStatus: resp.GetStatus().String(),
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.StatusMismatchError
+		res.ErrorMsg = fmt.Sprintf("Unexpected serving status %s", res.ServingStatus)
+	}
+
+	return res
+}
+
+// classifyGRPCError maps the status of a failed call to an error class. The
+// transport error behind an unavailable server only survives in its message.
+func classifyGRPCError(err error) string {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.DeadlineExceeded:
+		return sitestore.TimeoutError
+	case codes.Unavailable:
+		msg := st.Message()
+		switch {
+		case strings.Contains(msg, "authentication handshake failed"):
+			return sitestore.TLSError
+		case strings.Contains(msg, "no such host"):
+			return sitestore.DNSError
+		}
+		return sitestore.ConnectError
+	}
+
+	return sitestore.UnknownError
+}
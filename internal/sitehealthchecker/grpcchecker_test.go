@@ -0,0 +1,135 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serveGRPC runs a gRPC health server on a local port, over TLS when cert is
+// given, and returns its address
+func serveGRPC(t *testing.T, cert *tls.Certificate) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opts []grpc.ServerOption
+	if cert != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{*cert}})))
+	}
+
+	hs := health.NewServer()
+	hs.SetServingStatus("zempag.Users", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("zempag.Billing", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	srv := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return ln.Addr().String()
+}
+
+func TestCheckGRPC(t *testing.T) {
+	cert, roots := newTestCertificate(t, time.Now().Add(90*24*time.Hour), net.ParseIP("127.0.0.1"))
+
+	// Mocking
+	implementedRootCAs := rootCAs
+	defer func() {
+		rootCAs = implementedRootCAs
+	}()
+	rootCAs = roots
+
+	plaintext := "grpc://" + serveGRPC(t, nil)
+	secure := "grpcs://" + serveGRPC(t, &cert)
+
+	// Grab a free port and release it so that nothing listens on it
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := "grpc://" + ln.Addr().String()
+	ln.Close()
+
+	var testCases = []struct {
+		name             string
+		url              string
+		service          string
+		expStatus        int
+		expServingStatus string
+		expErrType       string
+	}{
+		{
+			name:             "Checking the server health",
+			url:              plaintext,
+			expStatus:        sitestore.Healthy,
+			expServingStatus: "SERVING",
+		},
+		{
+			name:             "Checking a serving service",
+			url:              plaintext,
+			service:          "zempag.Users",
+			expStatus:        sitestore.Healthy,
+			expServingStatus: "SERVING",
+		},
+		{
+			name:             "Checking a service that is not serving",
+			url:              plaintext,
+			service:          "zempag.Billing",
+			expStatus:        sitestore.Unhealthy,
+			expServingStatus: "NOT_SERVING",
+			expErrType:       sitestore.StatusMismatchError,
+		},
+		{
+			name:       "Checking an unknown service",
+			url:        plaintext,
+			service:    "zempag.Search",
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.UnknownError,
+		},
+		{
+			name:             "Checking a server over TLS",
+			url:              secure,
+			service:          "zempag.Users",
+			expStatus:        sitestore.Healthy,
+			expServingStatus: "SERVING",
+		},
+		{
+			name:       "Checking a TLS server in plaintext",
+			url:        "grpc" + secure[len("grpcs"):],
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.ConnectError,
+		},
+		{
+			name:       "Checking a closed port",
+			url:        closed,
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.ConnectError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: tc.url, CheckType: sitestore.GRPCCheck, GRPC: &sitestore.GRPCOptions{Service: tc.service}}
+			res := checkGRPC(context.Background(), site, time.Second)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ServingStatus != tc.expServingStatus {
+				t.Errorf("Expected serving status %q but got %q", tc.expServingStatus, res.ServingStatus)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrType, res.ErrorClass, res.ErrorMsg)
+			}
+		})
+	}
+}
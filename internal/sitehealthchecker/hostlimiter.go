@@ -0,0 +1,186 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimit represents how hard a single host may be probed. MaxInFlight caps
+// the checks running against the host at once, and Rate caps the checks
+// started per second, allowing bursts of up to Burst checks. A zero
+// MaxInFlight or Rate leaves that limit off, and a zero Burst is the same as a
+// Burst of 1, i.e. checks are started at Rate without bursts.
+type HostLimit struct {
+	MaxInFlight int     `json:"max_in_flight"`
+	Rate        float64 `json:"rate"`
+	Burst       int     `json:"burst"`
+}
+
+// Validate checks that the limits are not negative
+func (l HostLimit) Validate() error {
+	if l.MaxInFlight < 0 || l.Rate < 0 || l.Burst < 0 {
+		return errors.New("Host limits must not be negative")
+	}
+
+	return nil
+}
+
+// HostLimiter limits the checks run against every host. Hosts are limited by
+// their override when they have one, and by the default limit otherwise.
+type HostLimiter struct {
+	def       HostLimit
+	overrides map[string]HostLimit
+
+	hosts map[string]*hostState
+	sync.Mutex
+}
+
+// NewHostLimiter constructs a HostLimiter. Overrides are keyed by host name.
+func NewHostLimiter(def HostLimit, overrides map[string]HostLimit) (*HostLimiter, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+
+	l := &HostLimiter{
+		def:       def,
+		overrides: make(map[string]HostLimit),
+		hosts:     make(map[string]*hostState),
+	}
+	for host, o := range overrides {
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", host, err)
+		}
+		l.overrides[strings.ToLower(host)] = o
+	}
+
+	return l, nil
+}
+
+// LoadHostLimits reads per-host limit overrides from a JSON file mapping host
+// names to their limits
+func LoadHostLimits(path string) (map[string]HostLimit, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides map[string]HostLimit
+	if err := json.Unmarshal(b, &overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// Acquire waits until a check may run against host, or until ctx is done. The
+// returned release function must be called once the check is over.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	hs := l.state(strings.ToLower(host))
+
+	if hs.slots != nil {
+		select {
+		case hs.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if hs.slots != nil {
+			<-hs.slots
+		}
+	}
+
+	for {
+		d := hs.take(time.Now())
+		if d == 0 {
+			return release, nil
+		}
+
+		if !wait(ctx, d) {
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// state returns the state of host, creating it on first use
+func (l *HostLimiter) state(host string) *hostState {
+	l.Lock()
+	defer l.Unlock()
+
+	if hs, found := l.hosts[host]; found {
+		return hs
+	}
+
+	limit, found := l.overrides[host]
+	if !found {
+		limit = l.def
+	}
+
+	hs := &hostState{rate: limit.Rate, burst: float64(limit.Burst)}
+	if hs.burst < 1 {
+		hs.burst = 1
+	}
+	hs.tokens = hs.burst
+	if limit.MaxInFlight > 0 {
+		hs.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+
+	l.hosts[host] = hs
+	return hs
+}
+
+// hostState holds the in-flight slots and the token bucket of a host
+type hostState struct {
+	slots chan struct{}
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	sync.Mutex
+}
+
+// take takes a token from the bucket, refilled at rate tokens per second, and
+// returns 0. When the bucket is empty, it returns how long until the next
+// token instead.
+func (hs *hostState) take(now time.Time) time.Duration {
+	if hs.rate <= 0 {
+		return 0
+	}
+
+	hs.Lock()
+	defer hs.Unlock()
+
+	if !hs.last.IsZero() {
+		hs.tokens += now.Sub(hs.last).Seconds() * hs.rate
+		if hs.tokens > hs.burst {
+			hs.tokens = hs.burst
+		}
+	}
+	hs.last = now
+
+	if hs.tokens >= 1 {
+		hs.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - hs.tokens) / hs.rate * float64(time.Second))
+}
+
+// siteHost returns the host name a site URL points at
+func siteHost(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
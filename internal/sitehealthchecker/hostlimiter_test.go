@@ -0,0 +1,278 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestHostLimiter_MaxInFlight(t *testing.T) {
+	l, err := NewHostLimiter(HostLimit{MaxInFlight: 2}, map[string]HostLimit{"api.zempag.com": {MaxInFlight: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCases = []struct {
+		name   string
+		host   string
+		expMax int
+	}{
+		{
+			name:   "Limiting a host by the default limit",
+			host:   "zempag.com",
+			expMax: 2,
+		},
+		{
+			name:   "Limiting a host by its override",
+			host:   "API.zempag.com",
+			expMax: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				inFlight int
+				max      int
+				wg       sync.WaitGroup
+			)
+
+			for i := 0; i < 6; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					release, err := l.Acquire(context.Background(), tc.host)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer release()
+
+					mu.Lock()
+					inFlight++
+					if inFlight > max {
+						max = inFlight
+					}
+					mu.Unlock()
+
+					time.Sleep(10 * time.Millisecond)
+
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			// Expectations
+			if max != tc.expMax {
+				t.Errorf("Expected at most %d checks in flight but got %d", tc.expMax, max)
+			}
+		})
+	}
+}
+
+func TestHostLimiter_Rate(t *testing.T) {
+	l, err := NewHostLimiter(HostLimit{Rate: 2, Burst: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := l.state("zempag.com")
+
+	now := time.Now()
+	var testCases = []struct {
+		name    string
+		at      time.Time
+		expWait time.Duration
+	}{
+		{name: "Taking the first token of the burst", at: now, expWait: 0},
+		{name: "Taking the second token of the burst", at: now, expWait: 0},
+		{name: "Waiting for the bucket to refill", at: now, expWait: 500 * time.Millisecond},
+		{name: "Taking a refilled token", at: now.Add(500 * time.Millisecond), expWait: 0},
+		{name: "Refilling no more than the burst", at: now.Add(time.Hour), expWait: 0},
+		{name: "Taking the rest of the burst", at: now.Add(time.Hour), expWait: 0},
+		{name: "Waiting again once the burst is spent", at: now.Add(time.Hour), expWait: 500 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if wait := hs.take(tc.at); wait != tc.expWait {
+				t.Errorf("Expected to wait %v but got %v", tc.expWait, wait)
+			}
+		})
+	}
+}
+
+func TestHostLimiter_Cancelled(t *testing.T) {
+	l, err := NewHostLimiter(HostLimit{MaxInFlight: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := l.Acquire(context.Background(), "zempag.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.Acquire(ctx, "zempag.com"); err == nil {
+		t.Errorf("Expected waiting on a busy host to stop with its context but got nil")
+	}
+
+	if release, err := l.Acquire(context.Background(), "www.google.com"); err != nil {
+		t.Errorf("Expected other hosts not to be limited but got %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestNewHostLimiter(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		def       HostLimit
+		overrides map[string]HostLimit
+		hasErr    bool
+	}{
+		{
+			name:   "Limiting without overrides",
+			def:    HostLimit{MaxInFlight: 4},
+			hasErr: false,
+		},
+		{
+			name:   "Limiting with a negative default",
+			def:    HostLimit{Rate: -1},
+			hasErr: true,
+		},
+		{
+			name:      "Limiting with a negative override",
+			overrides: map[string]HostLimit{"zempag.com": {MaxInFlight: -1}},
+			hasErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHostLimiter(tc.def, tc.overrides)
+
+			if tc.hasErr && err == nil {
+				t.Errorf("Expected to return an error but got nil")
+			}
+
+			if !tc.hasErr && err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadHostLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	content := `{"api.zempag.com": {"max_in_flight": 1, "rate": 0.5, "burst": 2}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := LoadHostLimits(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o := overrides["api.zempag.com"]; o != (HostLimit{MaxInFlight: 1, Rate: 0.5, Burst: 2}) {
+		t.Errorf("Expected the api.zempag.com override to be loaded but got %+v", overrides)
+	}
+
+	if _, err := LoadHostLimits(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected loading a missing file to return an error but got nil")
+	}
+}
+
+func TestCheck_HostLimiter(t *testing.T) {
+	// Mocking
+	implementedLimiter := hostLimiter
+	defer func() {
+		hostLimiter = implementedLimiter
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	l, err := NewHostLimiter(HostLimit{MaxInFlight: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseHostLimiter(l)
+
+	// The only slot of the host is taken, so its checks wait until cancelled
+	release, _ := l.Acquire(context.Background(), "zempag.com")
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	res := check(ctx, sitestore.Site{URL: "https://zempag.com/a", CheckType: "counting"}, time.Second)
+
+	// Expectations
+	if res.Status != sitestore.Cancelled {
+		t.Errorf("Expected the check to be cancelled while waiting on its host but got %+v", res)
+	}
+
+	if c := checker.count("https://zempag.com/a"); c != 0 {
+		t.Errorf("Expected the site not to be checked but it was %d times", c)
+	}
+
+	res = check(context.Background(), sitestore.Site{URL: "https://www.google.com", CheckType: "counting"}, time.Second)
+	if res.Status != sitestore.Healthy || checker.count("https://www.google.com") != 1 {
+		t.Errorf("Expected other hosts to be checked but got %+v", res)
+	}
+}
+
+func TestCheck_HostLimiterTimeout(t *testing.T) {
+	// Mocking
+	implementedLimiter := hostLimiter
+	defer func() {
+		hostLimiter = implementedLimiter
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	l, err := NewHostLimiter(HostLimit{MaxInFlight: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseHostLimiter(l)
+
+	// The only slot of the host is taken for longer than the check timeout
+	release, _ := l.Acquire(context.Background(), "zempag.com")
+	defer release()
+
+	start := time.Now()
+	res := check(context.Background(), sitestore.Site{URL: "https://zempag.com/a", CheckType: "counting"}, 20*time.Millisecond)
+
+	// Expectations
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the wait on the host to be bounded by the timeout but it took %v", elapsed)
+	}
+
+	if res.Status != sitestore.Cancelled || res.ErrorClass != sitestore.LimitedError {
+		t.Errorf("Expected the check to be skipped as limited but got %+v", res)
+	}
+
+	if c := checker.count("https://zempag.com/a"); c != 0 {
+		t.Errorf("Expected the site not to be checked but it was %d times", c)
+	}
+}
@@ -0,0 +1,265 @@
+package sitehealthchecker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func init() {
+	Register(sitestore.HTTPCheck, CheckerFunc(checkHTTP))
+}
+
+func checkHTTP(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	expected, err := sitestore.ParseStatusCodes(site.ExpectedStatus)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	req, err := newRequest(ctx, site)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	pt := phaseTimer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), pt.trace()))
+
+	// Redirects are only followed when the site does not expect one
+	start := time.Now()
+	resp, err := siteChecker(req, timeout, !expected.MatchAny(300, 399))
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = time.Since(start)
+		res.Timings = pt.timings(time.Now())
+		return res
+	}
+
+	// Only the beginning of the body is kept for the assertions and content
+	// hashing, the rest is just counted
+	body, size, err := readBody(resp, len(site.BodyAssertions) > 0 || site.Content != nil)
+	end := time.Now()
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = end.Sub(start)
+		res.StatusCode = resp.StatusCode
+		res.BodySize = size
+		res.Timings = pt.timings(end)
+		return res
+	}
+
+	res := sitestore.CheckResult{
+		Status:     sitestore.Healthy,
+		Latency:    end.Sub(start),
+		StatusCode: resp.StatusCode,
+		BodySize:   size,
+		Timings:    pt.timings(end),
+	}
+
+	if !expected.Match(resp.StatusCode) {
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.StatusMismatchError
+		res.ErrorMsg = fmt.Sprintf("Unexpected status code %d, expected %s", resp.StatusCode, expectedStatus(site))
+	} else if err := assertBody(body, site.BodyAssertions); err != nil {
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.AssertionError
+		res.ErrorMsg = err.Error()
+	}
+
+	// Error pages are not hashed, they would count as content changes
+	if site.Content != nil && expected.Match(resp.StatusCode) {
+		hash, err := hashContent(body, *site.Content)
+		if err != nil && res.Status == sitestore.Healthy {
+			res.Status = sitestore.Unhealthy
+			res.ErrorClass = sitestore.AssertionError
+			res.ErrorMsg = err.Error()
+		}
+		res.ContentHash = hash
+	}
+
+	// The client already verified the chain during the handshake, only the
+	// expiry is left to look at
+	if site.TLS != nil && resp.TLS != nil {
+		cr := inspectCertificate(resp.TLS.PeerCertificates, req.URL.Hostname(), site.TLS, end, false)
+		res.Certificate = cr.Certificate
+		if res.Status == sitestore.Healthy && cr.Status != sitestore.Healthy {
+			res.Status = cr.Status
+			res.ErrorClass = cr.ErrorClass
+			res.ErrorMsg = cr.ErrorMsg
+		}
+	}
+
+	return res
+}
+
+// readBody reads the whole response body and closes it, keeping its
+// beginning when keep is set. A body cut short is an error.
+func readBody(resp *http.Response, keep bool) ([]byte, int64, error) {
+	if resp.Body == nil {
+		return nil, 0, nil
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	var size int64
+	if keep {
+		n, err := io.Copy(&body, io.LimitReader(resp.Body, maxAssertedBodySize))
+		size += n
+		if err != nil {
+			return body.Bytes(), size, err
+		}
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	size += n
+	return body.Bytes(), size, err
+}
+
+// newRequest builds the request of an HTTP check bound to ctx, resolving the
+// secrets it references
+func newRequest(ctx context.Context, site sitestore.Site) (*http.Request, error) {
+	if site.Request == nil {
+		return http.NewRequestWithContext(ctx, "GET", site.URL, nil)
+	}
+	r := site.Request
+
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	body, err := secretResolver.Expand(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, site.URL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range r.Headers {
+		v, err := secretResolver.Expand(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.EqualFold(name, "Host") {
+			req.Host = v
+		} else {
+			req.Header.Set(name, v)
+		}
+	}
+
+	if r.BearerToken != "" {
+		token, err := secretResolver.Expand(r.BearerToken)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if r.BasicAuthUser != "" {
+		password, err := secretResolver.Expand(r.BasicAuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(r.BasicAuthUser, password)
+	}
+
+	return req, nil
+}
+
+// phaseTimer accumulates the duration of every phase of an HTTP request. The
+// durations are summed so that redirects are accounted for, and the trace
+// hooks are guarded because the transport may call them concurrently.
+type phaseTimer struct {
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	t            sitestore.Timings
+	sync.Mutex
+}
+
+func (pt *phaseTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.t.DNSLookup += time.Since(pt.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.t.Connect += time.Since(pt.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.t.TLSHandshake += time.Since(pt.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			pt.Lock()
+			defer pt.Unlock()
+			pt.firstByte = time.Now()
+			pt.t.FirstByte += pt.firstByte.Sub(pt.wroteRequest)
+		},
+	}
+}
+
+// timings returns the collected durations, with the transfer phase running
+// from the last first response byte until end
+func (pt *phaseTimer) timings(end time.Time) *sitestore.Timings {
+	pt.Lock()
+	defer pt.Unlock()
+
+	t := pt.t
+	if !pt.firstByte.IsZero() {
+		t.Transfer = end.Sub(pt.firstByte)
+	}
+
+	return &t
+}
+
+func expectedStatus(site sitestore.Site) string {
+	if site.ExpectedStatus == "" {
+		return sitestore.DefaultExpectedStatus
+	}
+
+	return site.ExpectedStatus
+}
@@ -0,0 +1,295 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestCheckHTTP_Timings(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	// Mocking
+	implementedSiteChecker := siteChecker
+	defer func() {
+		siteChecker = implementedSiteChecker
+	}()
+
+	// Trust the test server certificate
+	siteChecker = func(req *http.Request, timeout time.Duration, _ bool) (*http.Response, error) {
+		client := ts.Client()
+		client.Timeout = timeout
+		return client.Do(req)
+	}
+
+	res := checkHTTP(context.Background(), sitestore.Site{URL: ts.URL}, 800*time.Millisecond)
+
+	if res.Status != sitestore.Healthy {
+		t.Fatalf("Expected site to be healthy but got %+v", res)
+	}
+
+	if res.BodySize != 2 {
+		t.Errorf("Expected body size of 2 but got %v", res.BodySize)
+	}
+
+	tm := res.Timings
+	if tm == nil {
+		t.Fatalf("Expected timings to be recorded but got nil")
+	}
+
+	if tm.Connect <= 0 {
+		t.Errorf("Expected connect duration to be recorded but got %v", tm.Connect)
+	}
+
+	if tm.TLSHandshake <= 0 {
+		t.Errorf("Expected TLS handshake duration to be recorded but got %v", tm.TLSHandshake)
+	}
+
+	if tm.FirstByte < 20*time.Millisecond {
+		t.Errorf("Expected first byte duration to include server time but got %v", tm.FirstByte)
+	}
+
+	if sum := tm.DNSLookup + tm.Connect + tm.TLSHandshake + tm.FirstByte + tm.Transfer; sum > res.Latency {
+		t.Errorf("Expected phases %v not to exceed total latency %v", sum, res.Latency)
+	}
+}
+
+func TestCheckHTTP_FreshConnections(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	// Every check of the same site opens its own connection
+	for i := 0; i < 2; i++ {
+		res := checkHTTP(context.Background(), sitestore.Site{URL: ts.URL}, 800*time.Millisecond)
+
+		if res.Status != sitestore.Healthy {
+			t.Fatalf("Expected site to be healthy but got %+v", res)
+		}
+
+		if res.Timings == nil || res.Timings.Connect <= 0 {
+			t.Errorf("Expected check %d to record its connect duration but got %+v", i+1, res.Timings)
+		}
+	}
+}
+
+func TestCheckHTTP_ExpectedStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		case "/private":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	var testCases = []struct {
+		name           string
+		path           string
+		expectedStatus string
+		expStatus      int
+		expStatusCode  int
+	}{
+		{
+			name:          "Following a redirect by default",
+			path:          "/moved",
+			expStatus:     sitestore.Healthy,
+			expStatusCode: 200,
+		},
+		{
+			name:           "Expecting a redirect",
+			path:           "/moved",
+			expectedStatus: "301",
+			expStatus:      sitestore.Healthy,
+			expStatusCode:  301,
+		},
+		{
+			name:           "Expecting an auth protected page",
+			path:           "/private",
+			expectedStatus: "200-299,401",
+			expStatus:      sitestore.Healthy,
+			expStatusCode:  401,
+		},
+		{
+			name:           "Expecting a status that is not returned",
+			path:           "/",
+			expectedStatus: "204",
+			expStatus:      sitestore.Unhealthy,
+			expStatusCode:  200,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: ts.URL + tc.path, ExpectedStatus: tc.expectedStatus}
+			res := checkHTTP(context.Background(), site, 800*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.StatusCode != tc.expStatusCode {
+				t.Errorf("Expected status code %v but got %v", tc.expStatusCode, res.StatusCode)
+			}
+
+			if tc.expStatus == sitestore.Unhealthy && res.ErrorClass != sitestore.StatusMismatchError {
+				t.Errorf("Expected a status mismatch but got %v", res.ErrorClass)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP_Request(t *testing.T) {
+	os.Setenv("GOHEALTH_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("GOHEALTH_TEST_TOKEN")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, password, _ := r.BasicAuth()
+
+		switch {
+		case r.URL.Path == "/bearer" && r.Header.Get("Authorization") == "Bearer s3cr3t":
+		case r.URL.Path == "/basic" && user == "gohealth" && password == "s3cr3t":
+		case r.URL.Path == "/post" && r.Method == "POST" && string(body) == `{"token":"s3cr3t"}` &&
+			r.Host == "internal.zempag.com" && r.UserAgent() == "gohealth/1.0":
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	var testCases = []struct {
+		name      string
+		path      string
+		request   *sitestore.HTTPRequest
+		expStatus int
+	}{
+		{
+			name:      "Sending a bearer token",
+			path:      "/bearer",
+			request:   &sitestore.HTTPRequest{BearerToken: "${env:GOHEALTH_TEST_TOKEN}"},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Sending basic auth",
+			path:      "/basic",
+			request:   &sitestore.HTTPRequest{BasicAuthUser: "gohealth", BasicAuthPassword: "${env:GOHEALTH_TEST_TOKEN}"},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name: "Sending a POST with headers and a body",
+			path: "/post",
+			request: &sitestore.HTTPRequest{
+				Method:  "POST",
+				Headers: map[string]string{"Host": "internal.zempag.com", "User-Agent": "gohealth/1.0"},
+				Body:    `{"token":"${env:GOHEALTH_TEST_TOKEN}"}`,
+			},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Referencing a missing secret",
+			path:      "/bearer",
+			request:   &sitestore.HTTPRequest{BearerToken: "${secret:token}"},
+			expStatus: sitestore.Unhealthy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: ts.URL + tc.path, Request: tc.request}
+			res := checkHTTP(context.Background(), site, 800*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP_Body(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		handler       http.HandlerFunc
+		site          sitestore.Site
+		expStatus     int
+		expErrorClass string
+	}{
+		{
+			name: "Checking a complete body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name: "Checking a truncated body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "100000")
+				w.Write([]byte("partial"))
+			},
+			expStatus:     sitestore.Unhealthy,
+			expErrorClass: sitestore.UnknownError,
+		},
+		{
+			name: "Checking a truncated body that is asserted",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "100000")
+				w.Write([]byte("partial"))
+			},
+			site:          sitestore.Site{BodyAssertions: []sitestore.BodyAssertion{{Type: "contains", Value: "partial"}}},
+			expStatus:     sitestore.Unhealthy,
+			expErrorClass: sitestore.UnknownError,
+		},
+		{
+			name: "Checking a body that stalls",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("slow"))
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			expStatus:     sitestore.Unhealthy,
+			expErrorClass: sitestore.TimeoutError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(tc.handler)
+			defer ts.Close()
+
+			site := tc.site
+			site.URL = ts.URL
+			res := checkHTTP(context.Background(), site, 200*time.Millisecond)
+
+			// Expectations
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %d but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrorClass {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrorClass, res.ErrorClass, res.ErrorMsg)
+			}
+
+			if res.Status == sitestore.Unhealthy && (res.StatusCode != 200 || res.Latency <= 0 || res.Timings == nil) {
+				t.Errorf("Expected the failure to record the status code, latency and timings but got %+v", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// mailPorts are the ports of mail servers whose URL has none
+var mailPorts = map[string]string{
+	"smtp":  "25",
+	"smtps": "465",
+	"imap":  "143",
+	"imaps": "993",
+}
+
+var (
+	// errNoStartTLS is returned when STARTTLS is required but not offered
+	errNoStartTLS = errors.New("STARTTLS is not offered")
+	// errUnexpectedReply is wrapped by the errors of unexpected IMAP replies
+	errUnexpectedReply = errors.New("Unexpected reply")
+)
+
+// mailSession talks to a mail server over conn. It returns the state of the
+// connection when it was upgraded with STARTTLS.
+type mailSession func(conn net.Conn, host string, startTLS bool, t *sitestore.Timings) (*tls.ConnectionState, error)
+
+func init() {
+	Register(sitestore.SMTPCheck, CheckerFunc(checkSMTP))
+	Register(sitestore.IMAPCheck, CheckerFunc(checkIMAP))
+}
+
+func checkSMTP(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return checkMail(ctx, site, timeout, smtpSession)
+}
+
+func checkIMAP(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	return checkMail(ctx, site, timeout, imapSession)
+}
+
+// checkMail connects to a mail server, over TLS for smtps and imaps URLs, and
+// runs session. The certificate of TLS connections is inspected like the one
+// of a TLS check.
+func checkMail(ctx context.Context, site sitestore.Site, timeout time.Duration, session mailSession) sitestore.CheckResult {
+	u, err := url.Parse(site.URL)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	host := u.Hostname()
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(host, mailPorts[u.Scheme])
+	}
+
+	var opts sitestore.MailOptions
+	if site.Mail != nil {
+		opts = *site.Mail
+	}
+
+	start := time.Now()
+	conn, err := dialTCP(ctx, addr, timeout)
+	t := &sitestore.Timings{Connect: time.Since(start)}
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = t.Connect
+		res.Timings = t
+		return res
+	}
+	defer conn.Close()
+	conn.SetDeadline(start.Add(timeout))
+
+	var state *tls.ConnectionState
+	if strings.HasSuffix(u.Scheme, "s") {
+		tlsConn, err := handshake(conn, host, t)
+		if err != nil {
+			res := failure(classifyError(err), err)
+			res.Latency = time.Since(start)
+			res.Timings = t
+			return res
+		}
+		conn = tlsConn
+		cs := tlsConn.ConnectionState()
+		state = &cs
+	}
+
+	upgraded, err := session(conn, host, opts.StartTLS, t)
+	if upgraded != nil {
+		state = upgraded
+	}
+
+	res := sitestore.CheckResult{Status: sitestore.Healthy}
+	if err != nil {
+		res = mailFailure(err)
+	} else if state != nil {
+		// The chain was verified during the handshake, only the expiry is
+		// left to look at
+		cr := inspectCertificate(state.PeerCertificates, host, site.TLS, time.Now(), false)
+		res.Certificate = cr.Certificate
+		res.Status = cr.Status
+		res.ErrorClass = cr.ErrorClass
+		res.ErrorMsg = cr.ErrorMsg
+	}
+
+	res.Latency = time.Since(start)
+	res.Timings = t
+	return res
+}
+
+// mailFailure classifies the error of a mail session
+func mailFailure(err error) sitestore.CheckResult {
+	var protoErr *textproto.Error
+	switch {
+	case errors.As(err, &protoErr):
+		res := failure(sitestore.StatusMismatchError, err)
+		res.StatusCode = protoErr.Code
+		return res
+	case errors.Is(err, errUnexpectedReply):
+		return failure(sitestore.StatusMismatchError, err)
+	case errors.Is(err, errNoStartTLS):
+		return failure(sitestore.AssertionError, err)
+	}
+
+	return failure(classifyError(err), err)
+}
+
+// handshake runs a verified TLS client handshake over conn
+func handshake(conn net.Conn, host string, t *sitestore.Timings) (*tls.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host, RootCAs: rootCAs})
+
+	start := time.Now()
+	err := tlsConn.Handshake()
+	t.TLSHandshake = time.Since(start)
+
+	return tlsConn, err
+}
+
+// smtpSession reads the 220 banner, issues EHLO and, when asked to, upgrades
+// the connection with STARTTLS
+func smtpSession(conn net.Conn, host string, startTLS bool, t *sitestore.Timings) (*tls.ConnectionState, error) {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Hello("gohealth"); err != nil {
+		return nil, err
+	}
+
+	// Failing to say goodbye does not make the server unhealthy
+	if !startTLS {
+		c.Quit()
+		return nil, nil
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return nil, errNoStartTLS
+	}
+
+	start := time.Now()
+	err = c.StartTLS(&tls.Config{ServerName: host, RootCAs: rootCAs})
+	t.TLSHandshake = time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _ := c.TLSConnectionState()
+	c.Quit()
+	return &state, nil
+}
+
+// imapSession reads the OK greeting, asks for the server capabilities and,
+// when asked to, upgrades the connection with STARTTLS
+func imapSession(conn net.Conn, host string, startTLS bool, t *sitestore.Timings) (*tls.ConnectionState, error) {
+	text := textproto.NewConn(conn)
+
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return nil, fmt.Errorf("%w %q", errUnexpectedReply, greeting)
+	}
+
+	caps, err := imapCommand(text, "a1", "CAPABILITY")
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to say goodbye does not make the server unhealthy
+	if !startTLS {
+		imapCommand(text, "a2", "LOGOUT")
+		return nil, nil
+	}
+
+	if !strings.Contains(strings.ToUpper(strings.Join(caps, " ")), " STARTTLS") {
+		return nil, errNoStartTLS
+	}
+
+	if _, err := imapCommand(text, "a2", "STARTTLS"); err != nil {
+		return nil, err
+	}
+
+	tlsConn, err := handshake(conn, host, t)
+	if err != nil {
+		return nil, err
+	}
+
+	state := tlsConn.ConnectionState()
+	imapCommand(textproto.NewConn(tlsConn), "a3", "LOGOUT")
+	return &state, nil
+}
+
+// imapCommand sends a tagged IMAP command and returns its untagged replies
+func imapCommand(text *textproto.Conn, tag, cmd string) ([]string, error) {
+	if err := text.PrintfLine("%s %s", tag, cmd); err != nil {
+		return nil, err
+	}
+
+	var untagged []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return untagged, err
+		}
+
+		if !strings.HasPrefix(line, tag+" ") {
+			untagged = append(untagged, line)
+			continue
+		}
+
+		if !strings.HasPrefix(line, tag+" OK") {
+			return untagged, fmt.Errorf("%w %q to %s", errUnexpectedReply, line, cmd)
+		}
+		return untagged, nil
+	}
+}
@@ -0,0 +1,199 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// serveSMTP runs a fake SMTP server greeting with banner, offering STARTTLS
+// with cert when given
+func serveSMTP(t *testing.T, banner string, cert *tls.Certificate) string {
+	addr := serveTCP(t, func(conn net.Conn) {
+		text := textproto.NewConn(conn)
+		text.PrintfLine("%s", banner)
+
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO":
+				if cert != nil {
+					text.PrintfLine("250-mail.zempag.test\r\n250-STARTTLS\r\n250 8BITMIME")
+				} else {
+					text.PrintfLine("250-mail.zempag.test\r\n250 8BITMIME")
+				}
+			case "STARTTLS":
+				text.PrintfLine("220 Ready to start TLS")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				text = textproto.NewConn(tlsConn)
+				cert = nil
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Command not implemented")
+			}
+		}
+	})
+
+	return strings.Replace(addr, "tcp://", "smtp://", 1)
+}
+
+// serveIMAP runs a fake IMAP server, offering STARTTLS with cert when given
+func serveIMAP(t *testing.T, cert *tls.Certificate) string {
+	addr := serveTCP(t, func(conn net.Conn) {
+		text := textproto.NewConn(conn)
+		text.PrintfLine("* OK IMAP4rev1 ready")
+
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return
+			}
+
+			tag := fields[0]
+			switch strings.ToUpper(fields[1]) {
+			case "CAPABILITY":
+				if cert != nil {
+					text.PrintfLine("* CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED")
+				} else {
+					text.PrintfLine("* CAPABILITY IMAP4rev1 AUTH=PLAIN")
+				}
+				text.PrintfLine("%s OK CAPABILITY completed", tag)
+			case "STARTTLS":
+				text.PrintfLine("%s OK Begin TLS negotiation now", tag)
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				text = textproto.NewConn(tlsConn)
+				cert = nil
+			case "LOGOUT":
+				text.PrintfLine("* BYE")
+				text.PrintfLine("%s OK LOGOUT completed", tag)
+				return
+			default:
+				text.PrintfLine("%s BAD Unknown command", tag)
+			}
+		}
+	})
+
+	return strings.Replace(addr, "tcp://", "imap://", 1)
+}
+
+func TestCheckMail(t *testing.T) {
+	cert, roots := newTestCertificate(t, time.Now().Add(90*24*time.Hour), net.ParseIP("127.0.0.1"))
+
+	// Mocking
+	implementedRootCAs := rootCAs
+	defer func() {
+		rootCAs = implementedRootCAs
+	}()
+	rootCAs = roots
+
+	untrusted := x509.NewCertPool()
+	startTLS := &sitestore.MailOptions{StartTLS: true}
+
+	var testCases = []struct {
+		name       string
+		site       sitestore.Site
+		roots      *x509.CertPool
+		expStatus  int
+		expErrType string
+		expCert    bool
+	}{
+		{
+			name:      "Reading an SMTP banner",
+			site:      sitestore.Site{URL: serveSMTP(t, "220 mail.zempag.test ESMTP", nil), CheckType: sitestore.SMTPCheck},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Reading an SMTP banner refusing service",
+			site:       sitestore.Site{URL: serveSMTP(t, "554 No SMTP service here", nil), CheckType: sitestore.SMTPCheck},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.StatusMismatchError,
+		},
+		{
+			name:      "Upgrading an SMTP connection with STARTTLS",
+			site:      sitestore.Site{URL: serveSMTP(t, "220 mail.zempag.test ESMTP", &cert), CheckType: sitestore.SMTPCheck, Mail: startTLS},
+			expStatus: sitestore.Healthy,
+			expCert:   true,
+		},
+		{
+			name:       "Requiring STARTTLS from an SMTP server not offering it",
+			site:       sitestore.Site{URL: serveSMTP(t, "220 mail.zempag.test ESMTP", nil), CheckType: sitestore.SMTPCheck, Mail: startTLS},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.AssertionError,
+		},
+		{
+			name:       "Upgrading an SMTP connection to an untrusted certificate",
+			site:       sitestore.Site{URL: serveSMTP(t, "220 mail.zempag.test ESMTP", &cert), CheckType: sitestore.SMTPCheck, Mail: startTLS},
+			roots:      untrusted,
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.TLSError,
+		},
+		{
+			name:      "Reading an IMAP greeting",
+			site:      sitestore.Site{URL: serveIMAP(t, nil), CheckType: sitestore.IMAPCheck},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Upgrading an IMAP connection with STARTTLS",
+			site:      sitestore.Site{URL: serveIMAP(t, &cert), CheckType: sitestore.IMAPCheck, Mail: startTLS},
+			expStatus: sitestore.Healthy,
+			expCert:   true,
+		},
+		{
+			name:       "Requiring STARTTLS from an IMAP server not offering it",
+			site:       sitestore.Site{URL: serveIMAP(t, nil), CheckType: sitestore.IMAPCheck, Mail: startTLS},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.AssertionError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootCAs = roots
+			if tc.roots != nil {
+				rootCAs = tc.roots
+			}
+
+			res := check(context.Background(), tc.site, time.Second)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrType, res.ErrorClass, res.ErrorMsg)
+			}
+
+			if (res.Certificate != nil) != tc.expCert {
+				t.Errorf("Expected certificate to be reported: %v but got %+v", tc.expCert, res.Certificate)
+			}
+
+			if tc.expCert && res.Timings.TLSHandshake <= 0 {
+				t.Errorf("Expected TLS handshake duration to be recorded but got %+v", res.Timings)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+const (
+	// DefaultWorkers is the number of workers of a pool sized without one.
+	// Checks mostly wait on the network, so there are many more workers than CPUs.
+	DefaultWorkers = 64
+	// DefaultQueueSize is the number of checks a pool sized without a queue
+	// size holds before submitting blocks
+	DefaultQueueSize = 1024
+)
+
+// Pool is a long-lived set of workers running site checks. Checks wait in a
+// bounded queue until a worker is free, and submitting blocks once the queue
+// is full, so that the number of goroutines does not grow with the number of
+// sites. A site is only queued once, until its result is recorded. A check
+// the host limits held back goes to the back of the queue again, so that it
+// runs once its host lets it through without parking a worker meanwhile.
+//
+// OnResult, when set, is called from the worker with every site once its
+// result is recorded. It must be set before the first check is submitted.
+type Pool struct {
+	OnResult func(sitestore.Site)
+
+	store   sitestore.Store
+	timeout time.Duration
+	workers int
+	jobs    chan job
+	wg      sync.WaitGroup
+
+	pendingMu sync.Mutex
+	pending   map[int]bool
+	idle      *sync.Cond
+
+	busy    int64
+	checked int64
+
+	waitMu    sync.Mutex
+	waits     int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// job is a site check waiting in the pool queue
+type job struct {
+	ctx      context.Context
+	site     sitestore.Site
+	queuedAt time.Time
+}
+
+// PoolStats represents the metrics of a pool. QueueDepth is the number of
+// checks waiting for a worker, and the waits run from a check being queued
+// until a worker picks it up.
+type PoolStats struct {
+	Workers     int           `json:"workers"`
+	QueueSize   int           `json:"queue_size"`
+	QueueDepth  int           `json:"queue_depth"`
+	Busy        int64         `json:"busy"`
+	Checked     int64         `json:"checked"`
+	AverageWait time.Duration `json:"average_wait"`
+	MaxWait     time.Duration `json:"max_wait"`
+}
+
+// NewPool constructs a Pool and starts its workers. Results are recorded in
+// store. A zero workers or queueSize falls back to DefaultWorkers or
+// DefaultQueueSize.
+func NewPool(store sitestore.Store, timeout time.Duration, workers, queueSize int) *Pool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	p := &Pool{
+		store:   store,
+		timeout: timeout,
+		workers: workers,
+		jobs:    make(chan job, queueSize),
+		pending: make(map[int]bool),
+	}
+	p.idle = sync.NewCond(&p.pendingMu)
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit queues a check of site without waiting for it to run. It returns
+// false, queuing nothing, when the site is still queued or being checked.
+// Once ctx is done, the check is recorded as cancelled without running.
+func (p *Pool) Submit(ctx context.Context, site sitestore.Site) bool {
+	p.pendingMu.Lock()
+	if p.pending[site.ID] {
+		p.pendingMu.Unlock()
+		return false
+	}
+	p.pending[site.ID] = true
+	p.pendingMu.Unlock()
+
+	j := job{ctx: ctx, site: site, queuedAt: time.Now()}
+	select {
+	case p.jobs <- j:
+	case <-ctx.Done():
+		p.run(j)
+	}
+
+	return true
+}
+
+// Wait waits until every submitted check is recorded
+func (p *Pool) Wait() {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	for len(p.pending) > 0 {
+		p.idle.Wait()
+	}
+}
+
+// Close waits for the submitted checks to be recorded, then stops the
+// workers. The pool must not be used afterwards.
+func (p *Pool) Close() {
+	p.Wait()
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// Stats returns the current metrics of the pool
+func (p *Pool) Stats() PoolStats {
+	st := PoolStats{
+		Workers:    p.workers,
+		QueueSize:  cap(p.jobs),
+		QueueDepth: len(p.jobs),
+		Busy:       atomic.LoadInt64(&p.busy),
+		Checked:    atomic.LoadInt64(&p.checked),
+	}
+
+	p.waitMu.Lock()
+	defer p.waitMu.Unlock()
+
+	st.MaxWait = p.maxWait
+	if p.waits > 0 {
+		st.AverageWait = p.totalWait / time.Duration(p.waits)
+	}
+
+	return st
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for j := range p.jobs {
+		p.run(j)
+	}
+}
+
+// run checks the site of a job, recording its wait and its result
+func (p *Pool) run(j job) {
+	wait := time.Since(j.queuedAt)
+	p.waitMu.Lock()
+	p.waits++
+	p.totalWait += wait
+	if wait > p.maxWait {
+		p.maxWait = wait
+	}
+	p.waitMu.Unlock()
+
+	atomic.AddInt64(&p.busy, 1)
+	res := check(j.ctx, j.site, p.timeout)
+	atomic.AddInt64(&p.busy, -1)
+
+	// The site stays pending while queued again, the queue cannot be closed
+	// before its check is recorded
+	if res.ErrorClass == sitestore.LimitedError && j.ctx.Err() == nil {
+		j.queuedAt = time.Now()
+		go func() { p.jobs <- j }()
+		return
+	}
+
+	p.store.UpdateResult(j.site.ID, res)
+	atomic.AddInt64(&p.checked, 1)
+
+	if p.OnResult != nil {
+		p.OnResult(j.site)
+	}
+
+	p.pendingMu.Lock()
+	delete(p.pending, j.site.ID)
+	if len(p.pending) == 0 {
+		p.idle.Broadcast()
+	}
+	p.pendingMu.Unlock()
+}
@@ -0,0 +1,199 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestPool_Submit(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	store := sitestore.NewStore()
+	for i := 0; i < 50; i++ {
+		store.Add(sitestore.Site{URL: fmt.Sprintf("https://zempag.com/%d", i), CheckType: "counting"})
+	}
+
+	pool := NewPool(&store, 800*time.Millisecond, 4, 8)
+	defer pool.Close()
+
+	for _, s := range store.List() {
+		pool.Submit(context.Background(), s)
+	}
+	pool.Wait()
+
+	// Expectations
+	for _, s := range store.List() {
+		if s.Status != sitestore.Healthy {
+			t.Errorf("Expected %v to be checked but got status %v", s.URL, s.Status)
+		}
+
+		if c := checker.count(s.URL); c != 1 {
+			t.Errorf("Expected %v to be checked once but it was %d times", s.URL, c)
+		}
+	}
+
+	st := pool.Stats()
+	if st.Workers != 4 || st.QueueSize != 8 {
+		t.Errorf("Expected 4 workers and a queue of 8 but got %+v", st)
+	}
+
+	if st.Checked != 50 || st.QueueDepth != 0 || st.Busy != 0 {
+		t.Errorf("Expected 50 checks and an idle pool but got %+v", st)
+	}
+}
+
+func TestPool_Stats(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "blocking")
+		registry.Unlock()
+	}()
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	Register("blocking", CheckerFunc(func(context.Context, sitestore.Site, time.Duration) sitestore.CheckResult {
+		started <- struct{}{}
+		<-release
+		return sitestore.CheckResult{Status: sitestore.Healthy}
+	}))
+
+	store := sitestore.NewStore()
+	for i := 0; i < 3; i++ {
+		store.Add(sitestore.Site{URL: fmt.Sprintf("https://zempag.com/%d", i), CheckType: "blocking"})
+	}
+
+	pool := NewPool(&store, 800*time.Millisecond, 1, 2)
+	defer pool.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for _, s := range store.List() {
+			pool.Submit(context.Background(), s)
+		}
+		pool.Wait()
+		close(done)
+	}()
+
+	// The single worker holds the first check while the others wait in the queue
+	<-started
+	deadline := time.Now().Add(time.Second)
+	for pool.Stats().QueueDepth != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if st := pool.Stats(); st.QueueDepth != 2 || st.Busy != 1 {
+		t.Errorf("Expected 2 queued checks and 1 busy worker but got %+v", st)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-done
+
+	if st := pool.Stats(); st.Checked != 3 || st.MaxWait < 20*time.Millisecond || st.AverageWait <= 0 {
+		t.Errorf("Expected queued checks to have waited at least 20ms but got %+v", st)
+	}
+}
+
+func TestPool_Cancelled(t *testing.T) {
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com"})
+	store.Add(sitestore.Site{URL: "https://www.google.com"})
+
+	pool := NewPool(&store, 800*time.Millisecond, 1, 1)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, s := range store.List() {
+		pool.Submit(ctx, s)
+	}
+	pool.Wait()
+
+	// Expectations
+	for _, s := range store.List() {
+		if h, _ := store.History(s.ID, time.Time{}, time.Time{}); len(h) != 1 || h[0].Status != sitestore.Cancelled {
+			t.Errorf("Expected %v check to be recorded as cancelled but got %+v", s.URL, h)
+		}
+	}
+}
+
+func TestPool_HostLimits(t *testing.T) {
+	// Mocking
+	implementedLimiter := hostLimiter
+	defer func() {
+		hostLimiter = implementedLimiter
+		registry.Lock()
+		delete(registry.checkers, "slow")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("slow", CheckerFunc(func(ctx context.Context, s sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+		time.Sleep(30 * time.Millisecond)
+		return checker.Check(ctx, s, timeout)
+	}))
+
+	var testCases = []struct {
+		name  string
+		limit HostLimit
+	}{
+		{
+			name:  "Limiting the checks in flight",
+			limit: HostLimit{MaxInFlight: 2},
+		},
+		{
+			name:  "Limiting the checks started per second",
+			limit: HostLimit{Rate: 50},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := NewHostLimiter(tc.limit, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			UseHostLimiter(l)
+
+			store := sitestore.NewStore()
+			for i := 0; i < 10; i++ {
+				store.Add(sitestore.Site{URL: fmt.Sprintf("https://zempag.com/%s/%d", tc.name, i), CheckType: "slow"})
+			}
+
+			// Checks wait on the host for longer than their timeout
+			pool := NewPool(&store, 10*time.Millisecond, 8, 16)
+			defer pool.Close()
+
+			ParallelHealthChecks(context.Background(), pool, 0)
+
+			// Expectations
+			for _, s := range store.List() {
+				if s.Status != sitestore.Healthy {
+					t.Errorf("Expected %v to be checked once its host let it through but got status %v", s.URL, s.Status)
+				}
+
+				if c := checker.count(s.URL); c != 1 {
+					t.Errorf("Expected %v to be checked once but it was %d times", s.URL, c)
+				}
+
+				if h, _ := store.History(s.ID, time.Time{}, time.Time{}); len(h) != 1 {
+					t.Errorf("Expected only the completed check of %v to be recorded but got %+v", s.URL, h)
+				}
+			}
+
+			if st := pool.Stats(); st.Checked != 10 {
+				t.Errorf("Expected 10 checks but got %+v", st)
+			}
+		})
+	}
+}
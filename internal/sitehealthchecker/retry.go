@@ -0,0 +1,113 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// retry runs a checker under the site retry policy, backing off between the
+// attempts until ctx is done. Retrying stops before it would outlast the site
+// interval, so that a check run is over by the time the site is due again.
+// Every attempt is recorded on the returned result, which is the one of the
+// last attempt.
+func retry(ctx context.Context, site sitestore.Site, checker Checker, timeout time.Duration) sitestore.CheckResult {
+	start := time.Now()
+	res := attempt(ctx, site, checker, timeout)
+	if site.Retry == nil {
+		return res
+	}
+
+	policy := site.Retry
+	backoff := time.Duration(policy.Backoff) * time.Millisecond
+	deadline := start.Add(retryBudget(site))
+
+	var attempts []sitestore.Attempt
+	for {
+		attempts = append(attempts, sitestore.Attempt{
+			Status:     res.Status,
+			Latency:    res.Latency,
+			ErrorClass: res.ErrorClass,
+			ErrorMsg:   res.ErrorMsg,
+		})
+
+		if res.Status != sitestore.Unhealthy || len(attempts) >= policy.Attempts || !policy.Retries(res.ErrorClass) {
+			break
+		}
+
+		if time.Now().Add(backoff + timeout).After(deadline) {
+			break
+		}
+
+		if !wait(ctx, backoff) {
+			break
+		}
+		if backoff *= 2; backoff > sitestore.MaxRetryBackoff*time.Millisecond {
+			backoff = sitestore.MaxRetryBackoff * time.Millisecond
+		}
+		res = attempt(ctx, site, checker, timeout)
+	}
+
+	res.Attempts = attempts
+	return res
+}
+
+// retryBudget returns how long a check run may last with its retries, that is
+// the site interval
+func retryBudget(site sitestore.Site) time.Duration {
+	if site.Interval > 0 {
+		return time.Duration(site.Interval) * time.Second
+	}
+
+	return DefaultInterval
+}
+
+// attempt runs a single check once the host limiter lets it through. The
+// limiter is not waited on for longer than timeout, a check it holds back
+// past that is reported as limited, so that a worker is not parked behind a
+// busy host. The pool queues limited checks again.
+func attempt(ctx context.Context, site sitestore.Site, checker Checker, timeout time.Duration) sitestore.CheckResult {
+	if hostLimiter == nil {
+		return checker.Check(ctx, site, timeout)
+	}
+
+	host := siteHost(site.URL)
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	release, err := hostLimiter.Acquire(acquireCtx, host)
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return failure(classifyError(ctx.Err()), ctx.Err())
+		}
+		return limited(host, timeout)
+	}
+	defer release()
+
+	return checker.Check(ctx, site, timeout)
+}
+
+// limited builds the result of a check the host limiter held back. Like a
+// cancelled check, it tells nothing about the site, and it is only kept in its
+// history when not checked by the pool.
+func limited(host string, timeout time.Duration) sitestore.CheckResult {
+	return sitestore.CheckResult{
+		Status:     sitestore.Cancelled,
+		ErrorClass: sitestore.LimitedError,
+		ErrorMsg:   fmt.Sprintf("Check was held back, host %s was over its limits for %s", host, timeout),
+	}
+}
+
+// wait sleeps for d, returning false when ctx is done first
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,118 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestRetry(t *testing.T) {
+	var (
+		connect  = sitestore.CheckResult{Status: sitestore.Unhealthy, ErrorClass: sitestore.ConnectError, ErrorMsg: "refused"}
+		mismatch = sitestore.CheckResult{Status: sitestore.Unhealthy, ErrorClass: sitestore.StatusMismatchError, ErrorMsg: "500"}
+		healthy  = sitestore.CheckResult{Status: sitestore.Healthy}
+	)
+
+	var testCases = []struct {
+		name        string
+		interval    int
+		retry       *sitestore.RetryOptions
+		results     []sitestore.CheckResult
+		expStatus   int
+		expAttempts int
+		expRetries  int
+	}{
+		{
+			name:        "Checking without a retry policy",
+			results:     []sitestore.CheckResult{connect, healthy},
+			expStatus:   sitestore.Unhealthy,
+			expAttempts: 0,
+			expRetries:  0,
+		},
+		{
+			name:        "Checking a healthy site once",
+			retry:       &sitestore.RetryOptions{Attempts: 3},
+			results:     []sitestore.CheckResult{healthy},
+			expStatus:   sitestore.Healthy,
+			expAttempts: 1,
+			expRetries:  0,
+		},
+		{
+			name:        "Recovering after 2 retries",
+			retry:       &sitestore.RetryOptions{Attempts: 3, Backoff: 1},
+			results:     []sitestore.CheckResult{connect, connect, healthy},
+			expStatus:   sitestore.Healthy,
+			expAttempts: 3,
+			expRetries:  2,
+		},
+		{
+			name:        "Giving up once every attempt failed",
+			retry:       &sitestore.RetryOptions{Attempts: 2},
+			results:     []sitestore.CheckResult{connect, connect, healthy},
+			expStatus:   sitestore.Unhealthy,
+			expAttempts: 2,
+			expRetries:  1,
+		},
+		{
+			name:        "Not retrying an error class left out of the default policy",
+			retry:       &sitestore.RetryOptions{Attempts: 3},
+			results:     []sitestore.CheckResult{mismatch, healthy},
+			expStatus:   sitestore.Unhealthy,
+			expAttempts: 1,
+			expRetries:  0,
+		},
+		{
+			name:        "Retrying an error class listed by the policy",
+			retry:       &sitestore.RetryOptions{Attempts: 3, ErrorClasses: []string{sitestore.StatusMismatchError}},
+			results:     []sitestore.CheckResult{mismatch, healthy},
+			expStatus:   sitestore.Healthy,
+			expAttempts: 2,
+			expRetries:  1,
+		},
+		{
+			name:        "Giving up before retrying would outlast the interval",
+			interval:    1,
+			retry:       &sitestore.RetryOptions{Attempts: 3, Backoff: 900},
+			results:     []sitestore.CheckResult{connect, healthy},
+			expStatus:   sitestore.Unhealthy,
+			expAttempts: 1,
+			expRetries:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Mocking
+			calls := 0
+			checker := CheckerFunc(func(context.Context, sitestore.Site, time.Duration) sitestore.CheckResult {
+				res := tc.results[calls]
+				calls++
+				return res
+			})
+
+			site := sitestore.Site{URL: "https://zempag.com", Interval: tc.interval, Retry: tc.retry}
+			res := retry(context.Background(), site, checker, 800*time.Millisecond)
+
+			// Expectations
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %d but got %d", tc.expStatus, res.Status)
+			}
+
+			if len(res.Attempts) != tc.expAttempts {
+				t.Errorf("Expected %d attempts to be recorded but got %+v", tc.expAttempts, res.Attempts)
+			}
+
+			if res.Retries() != tc.expRetries {
+				t.Errorf("Expected %d retries but got %d", tc.expRetries, res.Retries())
+			}
+
+			for i, a := range res.Attempts {
+				if a.Status != tc.results[i].Status || a.ErrorClass != tc.results[i].ErrorClass {
+					t.Errorf("Expected attempt %d to record %+v but got %+v", i, tc.results[i], a)
+				}
+			}
+		})
+	}
+}
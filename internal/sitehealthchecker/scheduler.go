@@ -0,0 +1,235 @@
+package sitehealthchecker
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// DefaultInterval is the time between two checks of a site without an interval
+const DefaultInterval = 15 * time.Second
+
+// Scheduler checks every stored site once its own interval has elapsed. The
+// next due time of each site is kept in a min-heap so that a round only has to
+// look at the sites at the top of the heap. The heap is only synced with the
+// store when its revision tells that sites were added or deleted, so that
+// rounds do not list every site.
+//
+// When Stagger is set, every site is given a deterministic offset within its
+// interval so that checks are spread evenly instead of all starting on the
+// same tick, and each site keeps that phase from one round to the next. Jitter
+// adds a random delay in [0, Jitter) to every due time. Due sites are queued
+// on the workers of the pool, and a site still queued or being checked when
+// it is due again is skipped until its next due time.
+//
+// A Scheduler is not safe for concurrent use, Tick and Run must be called
+// from a single goroutine.
+type Scheduler struct {
+	Stagger bool
+	Jitter  time.Duration
+	Clock   Clock
+
+	pool            *Pool
+	defaultInterval time.Duration
+	lookbackPeriod  time.Duration
+
+	rand     *rand.Rand
+	queue    dueQueue
+	entries  map[int]*dueEntry
+	sites    map[int]sitestore.Site
+	revision int64
+	synced   bool
+}
+
+// Clock tells the scheduler when to run the next round, it is replaced in tests
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewScheduler constructs a Scheduler checking the sites of the pool store on
+// its workers. Sites without an interval are checked every defaultInterval. A
+// site that was updated within lookbackPeriod when it is first scheduled is
+// not checked before that period is over.
+func NewScheduler(pool *Pool, defaultInterval, lookbackPeriod time.Duration) *Scheduler {
+	if defaultInterval <= 0 {
+		defaultInterval = DefaultInterval
+	}
+
+	return &Scheduler{
+		pool:            pool,
+		defaultInterval: defaultInterval,
+		lookbackPeriod:  lookbackPeriod,
+		Clock:           realClock{},
+		rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
+		entries:         make(map[int]*dueEntry),
+	}
+}
+
+// Run calls Tick every resolution until ctx is done. onRound is called with
+// the queued sites after every round that queued at least one site. Run
+// returns once ctx is done without waiting for the queued checks, which are
+// cut short and recorded as cancelled by the pool.
+func (sch *Scheduler) Run(ctx context.Context, resolution time.Duration, onRound func([]sitestore.Site)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-sch.Clock.After(resolution):
+			if sites := sch.Tick(ctx, now); len(sites) > 0 && onRound != nil {
+				onRound(sites)
+			}
+		}
+	}
+}
+
+// Tick queues a check of every site that is due at now on the pool and
+// returns the queued sites. The checks are cancelled once ctx is done.
+func (sch *Scheduler) Tick(ctx context.Context, now time.Time) []sitestore.Site {
+	queued := make([]sitestore.Site, 0)
+	for _, s := range sch.due(now) {
+		if sch.pool.Submit(ctx, s) {
+			queued = append(queued, s)
+		}
+	}
+
+	return queued
+}
+
+// due syncs the heap with the store when sites were added or deleted, then
+// pops the sites due at now and pushes them back with their next due time
+func (sch *Scheduler) due(now time.Time) []sitestore.Site {
+	if rev := sch.pool.store.Revision(); !sch.synced || rev != sch.revision {
+		sch.sync(now)
+		sch.revision = rev
+		sch.synced = true
+	}
+
+	due := make([]sitestore.Site, 0)
+	for len(sch.queue) > 0 && !sch.queue[0].next.After(now) {
+		e := sch.queue[0]
+		s := sch.sites[e.siteID]
+		due = append(due, s)
+
+		e.next = sch.nextDue(e.next, now, sch.interval(s))
+		heap.Fix(&sch.queue, 0)
+	}
+
+	return due
+}
+
+// sync pushes the sites new to the store on the heap, and removes the deleted
+// ones from it
+func (sch *Scheduler) sync(now time.Time) {
+	sch.sites = make(map[int]sitestore.Site)
+	for _, s := range sch.pool.store.List() {
+		sch.sites[s.ID] = s
+
+		if _, found := sch.entries[s.ID]; !found {
+			e := &dueEntry{siteID: s.ID, next: now}
+			if sch.lookbackPeriod > 0 && !s.UpdatedAt.IsZero() && s.UpdatedAt.Add(sch.lookbackPeriod).After(now) {
+				e.next = s.UpdatedAt.Add(sch.lookbackPeriod)
+			}
+			if sch.Stagger {
+				e.next = e.next.Add(sch.offset(s))
+			}
+			e.next = e.next.Add(sch.jitter())
+			sch.entries[s.ID] = e
+			heap.Push(&sch.queue, e)
+		}
+	}
+
+	for id, e := range sch.entries {
+		if _, found := sch.sites[id]; !found {
+			heap.Remove(&sch.queue, e.index)
+			delete(sch.entries, id)
+		}
+	}
+}
+
+func (sch *Scheduler) interval(s sitestore.Site) time.Duration {
+	if s.Interval > 0 {
+		return time.Duration(s.Interval) * time.Second
+	}
+
+	return sch.defaultInterval
+}
+
+// nextDue returns the time a site that was due at prev and checked at now is
+// due again. Staggered sites keep their phase unless they fell a whole
+// interval behind.
+func (sch *Scheduler) nextDue(prev, now time.Time, interval time.Duration) time.Time {
+	next := now.Add(interval)
+	if sch.Stagger {
+		if n := prev.Add(interval); n.After(now) {
+			next = n
+		}
+	}
+
+	return next.Add(sch.jitter())
+}
+
+// offset spreads sites across their interval. Consecutive IDs are multiplied
+// by the golden ratio so that offsets stay evenly spaced however many sites
+// are added.
+func (sch *Scheduler) offset(s sitestore.Site) time.Duration {
+	const phi = 0.6180339887498949
+	_, frac := math.Modf(float64(s.ID) * phi)
+	return time.Duration(frac * float64(sch.interval(s)))
+}
+
+func (sch *Scheduler) jitter() time.Duration {
+	if sch.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(sch.rand.Int63n(int64(sch.Jitter)))
+}
+
+// dueEntry is a site waiting in the scheduler heap
+type dueEntry struct {
+	siteID int
+	next   time.Time
+	index  int
+}
+
+// dueQueue implements heap.Interface ordered by next due time
+type dueQueue []*dueEntry
+
+func (q dueQueue) Len() int { return len(q) }
+
+func (q dueQueue) Less(i, j int) bool {
+	if q[i].next.Equal(q[j].next) {
+		return q[i].siteID < q[j].siteID
+	}
+	return q[i].next.Before(q[j].next)
+}
+
+func (q dueQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *dueQueue) Push(x interface{}) {
+	e := x.(*dueEntry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *dueQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return e
+}
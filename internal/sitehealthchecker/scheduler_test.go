@@ -0,0 +1,323 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// countingChecker records how many times each site URL was checked
+type countingChecker struct {
+	counts map[string]int
+	sync.Mutex
+}
+
+func (c *countingChecker) Check(_ context.Context, s sitestore.Site, _ time.Duration) sitestore.CheckResult {
+	c.Lock()
+	defer c.Unlock()
+
+	c.counts[s.URL]++
+	return sitestore.CheckResult{Status: sitestore.Healthy}
+}
+
+func (c *countingChecker) count(url string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.counts[url]
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://api.zempag.com", CheckType: "counting", Interval: 5})
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "counting", Interval: 300})
+	store.Add(sitestore.Site{URL: "https://koprol.com", CheckType: "counting"})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+
+	start := time.Now()
+	for i := 0; i <= 60; i++ {
+		sch.Tick(context.Background(), start.Add(time.Duration(i)*time.Second))
+		pool.Wait()
+	}
+
+	// Checked on the first tick, then once every interval over the next minute
+	var testCases = []struct {
+		url string
+		exp int
+	}{
+		{url: "https://api.zempag.com", exp: 13},
+		{url: "https://zempag.com", exp: 1},
+		{url: "https://koprol.com", exp: 5},
+	}
+
+	for _, tc := range testCases {
+		if c := checker.count(tc.url); c != tc.exp {
+			t.Errorf("Expected %v to be checked %d times but it was %d", tc.url, tc.exp, c)
+		}
+	}
+}
+
+func TestScheduler_StoreChanges(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "counting", Interval: 10})
+	store.Add(sitestore.Site{
+		URL:       "https://koprol.com",
+		CheckType: "counting",
+		Interval:  10,
+		UpdatedAt: time.Now().Add(time.Duration(-5) * time.Second),
+	})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 15*time.Second)
+
+	now := time.Now()
+	if due := sch.Tick(context.Background(), now); len(due) != 1 || due[0].URL != "https://zempag.com" {
+		t.Errorf("Expected only the site outside the lookback period to be due but got %v", due)
+	}
+
+	store.Delete(1)
+	store.Add(sitestore.Site{URL: "https://www.google.com", CheckType: "counting", Interval: 10})
+
+	if due := sch.Tick(context.Background(), now.Add(time.Second)); len(due) != 1 || due[0].URL != "https://www.google.com" {
+		t.Errorf("Expected the new site to be due right away but got %v", due)
+	}
+
+	if due := sch.Tick(context.Background(), now.Add(10*time.Second)); len(due) != 1 || due[0].URL != "https://koprol.com" {
+		t.Errorf("Expected the deleted site to be unscheduled but got %v", due)
+	}
+}
+
+// listCountingStore counts how many times every site is listed
+type listCountingStore struct {
+	*sitestore.MemoryStore
+	lists int
+}
+
+func (s *listCountingStore) List() []sitestore.Site {
+	s.lists++
+	return s.MemoryStore.List()
+}
+
+func TestScheduler_Sync(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	mem := sitestore.NewStore()
+	mem.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "counting", Interval: 5})
+	store := &listCountingStore{MemoryStore: &mem}
+
+	pool := NewPool(store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		sch.Tick(context.Background(), now.Add(time.Duration(i)*time.Second))
+		pool.Wait()
+	}
+
+	if store.lists != 1 {
+		t.Errorf("Expected the sites to be listed once while none changed but they were %d times", store.lists)
+	}
+
+	mem.Add(sitestore.Site{URL: "https://koprol.com", CheckType: "counting", Interval: 5})
+	if due := sch.Tick(context.Background(), now.Add(10*time.Second)); len(due) != 2 {
+		t.Errorf("Expected the new site to be due along with the other one but got %v", due)
+	}
+	pool.Wait()
+
+	if store.lists != 2 {
+		t.Errorf("Expected the sites to be listed again once a site was added but they were %d times", store.lists)
+	}
+}
+
+func TestScheduler_Stagger(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	store := sitestore.NewStore()
+	for _, url := range []string{"https://a.zempag.com", "https://b.zempag.com", "https://c.zempag.com", "https://d.zempag.com", "https://e.zempag.com"} {
+		store.Add(sitestore.Site{URL: url, CheckType: "counting", Interval: 10})
+	}
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+	sch.Stagger = true
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if due := sch.Tick(context.Background(), start.Add(time.Duration(i)*time.Second)); len(due) > 1 {
+			t.Errorf("Expected checks to be spread across the interval but %d sites were due at %ds", len(due), i)
+		}
+		pool.Wait()
+	}
+
+	for _, url := range []string{"https://a.zempag.com", "https://e.zempag.com"} {
+		if c := checker.count(url); c != 3 {
+			t.Errorf("Expected %v to be checked 3 times but it was %d", url, c)
+		}
+	}
+}
+
+func TestScheduler_InFlight(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "blocking")
+		registry.Unlock()
+	}()
+	release := make(chan struct{})
+	Register("blocking", CheckerFunc(func(context.Context, sitestore.Site, time.Duration) sitestore.CheckResult {
+		<-release
+		return sitestore.CheckResult{Status: sitestore.Healthy}
+	}))
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "blocking", Interval: 5})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+
+	now := time.Now()
+	if due := sch.Tick(context.Background(), now); len(due) != 1 {
+		t.Errorf("Expected the site to be queued on the first tick but got %v", due)
+	}
+
+	// The first check is still running when the site is due again
+	if due := sch.Tick(context.Background(), now.Add(5*time.Second)); len(due) != 0 {
+		t.Errorf("Expected the site in flight not to be queued twice but got %v", due)
+	}
+
+	close(release)
+	pool.Wait()
+
+	if due := sch.Tick(context.Background(), now.Add(10*time.Second)); len(due) != 1 {
+		t.Errorf("Expected the site to be queued once its check is over but got %v", due)
+	}
+	pool.Wait()
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", Interval: 10})
+
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+	sch.Jitter = 2 * time.Second
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		next := sch.nextDue(now, now, 10*time.Second)
+		if next.Before(now.Add(10*time.Second)) || !next.Before(now.Add(12*time.Second)) {
+			t.Fatalf("Expected next due time to be jittered within 2s of the interval but got %v", next.Sub(now))
+		}
+	}
+}
+
+// fakeClock is a Clock whose time only moves when the test advances it
+type fakeClock struct {
+	now   time.Time
+	after chan time.Time
+}
+
+func (c *fakeClock) After(time.Duration) <-chan time.Time { return c.after }
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.after <- c.now
+}
+
+func TestScheduler_Run(t *testing.T) {
+	// Mocking
+	defer func() {
+		registry.Lock()
+		delete(registry.checkers, "counting")
+		registry.Unlock()
+	}()
+	checker := &countingChecker{counts: make(map[string]int)}
+	Register("counting", checker)
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: "https://zempag.com", CheckType: "counting", Interval: 10})
+
+	clock := &fakeClock{now: time.Now(), after: make(chan time.Time)}
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	sch := NewScheduler(pool, 15*time.Second, 0)
+	sch.Clock = clock
+
+	rounds := make(chan int, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sch.Run(ctx, time.Second, func(sites []sitestore.Site) {
+			pool.Wait()
+			rounds <- len(sites)
+		})
+		close(done)
+	}()
+
+	for i := 0; i < 21; i++ {
+		clock.advance(time.Second)
+	}
+	// A tick with nothing due only goes through once the last round is over,
+	// so that cancelling does not cut it short
+	clock.advance(time.Second)
+	cancel()
+	<-done
+
+	if len(rounds) != 3 {
+		t.Errorf("Expected 3 rounds over 21 fake seconds but got %d", len(rounds))
+	}
+
+	if c := checker.count("https://zempag.com"); c != 3 {
+		t.Errorf("Expected site to be checked 3 times but it was %d", c)
+	}
+}
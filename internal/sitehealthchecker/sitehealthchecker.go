@@ -1,8 +1,8 @@
 package sitehealthchecker
 
 import (
+	"context"
 	"net/http"
-	"runtime"
 	"time"
 
 	"github.com/levady/gohealth/internal/platform/sitestore"
@@ -10,58 +10,51 @@ import (
 
 var siteChecker = checkSiteWithTimeout
 
-// SerialHealthChecks run health checks on all stored Sites in serial
-func SerialHealthChecks(store *sitestore.Store, timeout time.Duration) {
+// checkTransport is the transport of HTTP checks. Keep-alives are off so that
+// every check resolves, connects and handshakes again, instead of reusing a
+// pooled connection that would hide those phases and their failures.
+var checkTransport = newCheckTransport()
+
+func newCheckTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DisableKeepAlives = true
+	return t
+}
+
+// SerialHealthChecks run health checks on all stored Sites in serial. Once ctx
+// is done, the sites left are recorded as cancelled.
+func SerialHealthChecks(ctx context.Context, store sitestore.Store, timeout time.Duration) {
 	for _, s := range store.List() {
-		resp, err := siteChecker(s.URL, timeout)
-		if err != nil || resp.StatusCode != 200 {
-			store.UpdateHealth(s.ID, sitestore.Unhealthy)
-		} else {
-			store.UpdateHealth(s.ID, sitestore.Healthy)
-		}
+		store.UpdateResult(s.ID, check(ctx, s, timeout))
 	}
 }
 
-// ParallelHealthChecks run health checks on all stored Sites in parallel
-func ParallelHealthChecks(store *sitestore.Store, timeout time.Duration, lookbackPeriod int) {
+// ParallelHealthChecks run health checks on all stored Sites on the workers of
+// pool, and returns once the pool is idle. Sites already queued on the pool
+// are not checked twice. Once ctx is done, in-flight checks are aborted and
+// they and the sites left are recorded as cancelled.
+func ParallelHealthChecks(ctx context.Context, pool *Pool, lookbackPeriod int) {
 	var sites []sitestore.Site
 
 	if lookbackPeriod == 0 {
-		sites = store.List()
+		sites = pool.store.List()
 	} else {
-		sites = store.ListFilter(lookbackPeriod)
+		sites = pool.store.ListFilter(lookbackPeriod)
 	}
 
-	sitesLen := len(sites)
-	resultCh := make(chan bool, len(sites))
-
-	grs := runtime.NumCPU()
-	batchCh := make(chan bool, grs)
-
-	for idx := 0; idx < sitesLen; idx++ {
-		go func(i int) {
-			batchCh <- true
-			{
-				s := sites[i]
-				resp, err := siteChecker(s.URL, timeout)
-				if err != nil || resp.StatusCode != 200 {
-					store.UpdateHealth(s.ID, sitestore.Unhealthy)
-				} else {
-					store.UpdateHealth(s.ID, sitestore.Healthy)
-				}
-				resultCh <- true
-			}
-			<-batchCh
-		}(idx)
+	for _, s := range sites {
+		pool.Submit(ctx, s)
 	}
+	pool.Wait()
+}
 
-	for sitesLen > 0 {
-		<-resultCh
-		sitesLen--
+func checkSiteWithTimeout(req *http.Request, timeout time.Duration, followRedirects bool) (*http.Response, error) {
+	client := http.Client{Transport: checkTransport, Timeout: timeout}
+	if !followRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
-}
 
-func checkSiteWithTimeout(url string, timeout time.Duration) (*http.Response, error) {
-	client := http.Client{Timeout: timeout}
-	return client.Get(url)
+	return client.Do(req)
 }
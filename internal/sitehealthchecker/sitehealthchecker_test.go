@@ -1,8 +1,10 @@
 package sitehealthchecker
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -25,8 +27,8 @@ func TestParallelHealthChecks(t *testing.T) {
 	store.Add(site2)
 	store.Add(site3)
 
-	siteChecker = func(url string, _ time.Duration) (*http.Response, error) {
-		switch url {
+	siteChecker = func(req *http.Request, _ time.Duration, _ bool) (*http.Response, error) {
+		switch req.URL.String() {
 		case "https://zempag.com":
 			return &http.Response{}, errors.New("Timeout")
 		case "https://www.google.com":
@@ -36,7 +38,10 @@ func TestParallelHealthChecks(t *testing.T) {
 		}
 	}
 
-	ParallelHealthChecks(&store, 800*time.Millisecond, 0)
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	ParallelHealthChecks(context.Background(), pool, 0)
 
 	sites := store.List()
 
@@ -51,6 +56,18 @@ func TestParallelHealthChecks(t *testing.T) {
 	if s := sites[2]; s.Status == sitestore.Unhealthy {
 		t.Errorf("Expected Site3 %v to be healthy.", s.URL)
 	}
+
+	if r := sites[0].LastResult; r == nil || r.ErrorMsg != "Timeout" {
+		t.Errorf("Expected Site1 result to record the error message but got %+v", r)
+	}
+
+	if r := sites[1].LastResult; r == nil || r.StatusCode != 500 || r.ErrorClass != sitestore.StatusMismatchError {
+		t.Errorf("Expected Site2 result to record a status mismatch but got %+v", r)
+	}
+
+	if r := sites[2].LastResult; r == nil || r.StatusCode != 200 || r.ErrorClass != "" {
+		t.Errorf("Expected Site3 result to record a 200 without error but got %+v", r)
+	}
 }
 
 func TestParallelHealthChecksWithLookbackPeriod(t *testing.T) {
@@ -69,8 +86,8 @@ func TestParallelHealthChecksWithLookbackPeriod(t *testing.T) {
 	store.Add(site2)
 	store.Add(site3)
 
-	siteChecker = func(url string, _ time.Duration) (*http.Response, error) {
-		switch url {
+	siteChecker = func(req *http.Request, _ time.Duration, _ bool) (*http.Response, error) {
+		switch req.URL.String() {
 		case "https://zempag.com":
 			return &http.Response{}, errors.New("Timeout")
 		case "https://www.google.com":
@@ -80,7 +97,10 @@ func TestParallelHealthChecksWithLookbackPeriod(t *testing.T) {
 		}
 	}
 
-	ParallelHealthChecks(&store, 800*time.Millisecond, 15)
+	pool := NewPool(&store, 800*time.Millisecond, 0, 0)
+	defer pool.Close()
+
+	ParallelHealthChecks(context.Background(), pool, 15)
 
 	sites := store.List()
 
@@ -101,3 +121,50 @@ func TestParallelHealthChecksWithLookbackPeriod(t *testing.T) {
 		t.Errorf("Expected Site3 %v to be healthy.", s.URL)
 	}
 }
+
+func TestParallelHealthChecks_Cancelled(t *testing.T) {
+	// Mocking
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	store := sitestore.NewStore()
+	store.Add(sitestore.Site{URL: ts.URL})
+	store.Add(sitestore.Site{URL: ts.URL + "/slow", Retry: &sitestore.RetryOptions{Attempts: 3, Backoff: sitestore.MaxRetryBackoff}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	pool := NewPool(&store, 10*time.Second, 0, 0)
+	defer pool.Close()
+
+	ParallelHealthChecks(ctx, pool, 0)
+
+	// Expectations
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancelling to abort the in-flight checks but they took %v", elapsed)
+	}
+
+	for _, s := range store.List() {
+		if s.Status != sitestore.Unknown {
+			t.Errorf("Expected %v status not to be updated but got %v", s.URL, s.Status)
+		}
+
+		if s.LastResult != nil {
+			t.Errorf("Expected %v last result not to be updated but got %+v", s.URL, s.LastResult)
+		}
+
+		h, _ := store.History(s.ID, time.Time{}, time.Time{})
+		if len(h) != 1 || h[0].Status != sitestore.Cancelled || h[0].ErrorClass != sitestore.CancelledError {
+			t.Errorf("Expected %v check to be recorded as cancelled but got %+v", s.URL, h)
+		}
+	}
+
+	// Sites checked once the round is cancelled are not probed at all
+	SerialHealthChecks(ctx, &store, 10*time.Second)
+	if h, _ := store.History(1, time.Time{}, time.Time{}); len(h) != 2 || h[1].Status != sitestore.Cancelled || h[1].Latency != 0 {
+		t.Errorf("Expected the check to be cancelled before probing but got %+v", h)
+	}
+}
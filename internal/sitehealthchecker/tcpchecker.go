@@ -0,0 +1,102 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// maxTCPReplySize is the number of reply bytes a TCP check waits for at most
+const maxTCPReplySize = 64 << 10
+
+func init() {
+	Register(sitestore.TCPCheck, CheckerFunc(checkTCP))
+}
+
+func checkTCP(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	u, err := url.Parse(site.URL)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	var opts sitestore.TCPOptions
+	if site.TCP != nil {
+		opts = *site.TCP
+	}
+
+	expect, err := regexp.Compile(opts.Expect)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	start := time.Now()
+	conn, err := dialTCP(ctx, u.Host, timeout)
+	connect := time.Since(start)
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = connect
+		res.Timings = &sitestore.Timings{Connect: connect}
+		return res
+	}
+	defer conn.Close()
+
+	res := sitestore.CheckResult{
+		Status:  sitestore.Healthy,
+		Timings: &sitestore.Timings{Connect: connect},
+	}
+
+	conn.SetDeadline(start.Add(timeout))
+
+	if opts.Send != "" {
+		if _, err := conn.Write([]byte(opts.Send)); err != nil {
+			res = failure(classifyError(err), err)
+			res.Latency = time.Since(start)
+			res.Timings = &sitestore.Timings{Connect: connect}
+			return res
+		}
+	}
+
+	if opts.Expect != "" {
+		reply, err := readUntil(conn, expect)
+		res.BodySize = int64(len(reply))
+		if err != nil {
+			res.Status = sitestore.Unhealthy
+			res.ErrorClass = classifyError(err)
+			if res.ErrorClass == sitestore.UnknownError {
+				res.ErrorClass = sitestore.AssertionError
+			}
+			res.ErrorMsg = fmt.Sprintf("Reply does not match %q: %v", opts.Expect, err)
+		}
+	}
+
+	res.Latency = time.Since(start)
+	return res
+}
+
+// readUntil reads from conn until what was read matches re. It gives up when
+// the connection is closed, its deadline passes or the reply grows too big.
+func readUntil(conn net.Conn, re *regexp.Regexp) ([]byte, error) {
+	var reply []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if re.Match(reply) {
+			return reply, nil
+		}
+
+		if err != nil {
+			return reply, err
+		}
+
+		if len(reply) >= maxTCPReplySize {
+			return reply, errors.New("reply is too big")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sitehealthchecker
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// serveTCP accepts connections on a local port and hands them to handle
+func serveTCP(t *testing.T, handle func(net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestCheckTCP(t *testing.T) {
+	banner := serveTCP(t, func(conn net.Conn) {
+		conn.Write([]byte("SSH-2.0-OpenSSH_8.9\r\n"))
+	})
+	echo := serveTCP(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	})
+	silent := serveTCP(t, func(conn net.Conn) {
+		time.Sleep(time.Second)
+	})
+
+	// Grab a free port and release it so that nothing listens on it
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := "tcp://" + ln.Addr().String()
+	ln.Close()
+
+	var testCases = []struct {
+		name       string
+		url        string
+		opts       *sitestore.TCPOptions
+		expStatus  int
+		expErrType string
+	}{
+		{
+			name:      "Connecting without options",
+			url:       silent,
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Expecting a banner",
+			url:       banner,
+			opts:      &sitestore.TCPOptions{Expect: `^SSH-2\.0-`},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Expecting the wrong banner",
+			url:        banner,
+			opts:       &sitestore.TCPOptions{Expect: `^220 `},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.AssertionError,
+		},
+		{
+			name:      "Sending a payload and expecting its echo",
+			url:       echo,
+			opts:      &sitestore.TCPOptions{Send: "PING\n", Expect: "PING"},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Expecting a reply that never comes",
+			url:        silent,
+			opts:       &sitestore.TCPOptions{Expect: "."},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.TimeoutError,
+		},
+		{
+			name:       "Connecting to a closed port",
+			url:        closed,
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.ConnectError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: tc.url, CheckType: sitestore.TCPCheck, TCP: tc.opts}
+			res := checkTCP(context.Background(), site, 200*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q", tc.expErrType, res.ErrorClass)
+			}
+
+			if tc.expErrType != sitestore.ConnectError && (res.Timings == nil || res.Timings.Connect <= 0) {
+				t.Errorf("Expected connect latency to be recorded but got %+v", res.Timings)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// rootCAs is the pool certificate chains are verified against, nil meaning
+// the system pool. It is replaced in tests.
+var rootCAs *x509.CertPool
+
+func init() {
+	Register(sitestore.TLSCheck, CheckerFunc(checkTLS))
+}
+
+func checkTLS(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	u, err := url.Parse(site.URL)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	start := time.Now()
+	rawConn, err := dialTCP(ctx, addr, timeout)
+	connect := time.Since(start)
+	if err != nil {
+		res := failure(classifyError(err), err)
+		res.Latency = connect
+		res.Timings = &sitestore.Timings{Connect: connect}
+		return res
+	}
+	defer rawConn.Close()
+	rawConn.SetDeadline(start.Add(timeout))
+
+	// The chain is verified by inspectCertificate rather than during the
+	// handshake, so that an invalid certificate can still be reported on
+	conn := tls.Client(rawConn, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true})
+	handshakeStart := time.Now()
+	err = conn.Handshake()
+	timings := &sitestore.Timings{Connect: connect, TLSHandshake: time.Since(handshakeStart)}
+	if err != nil {
+		res := failure(sitestore.TLSError, err)
+		res.Latency = time.Since(start)
+		res.Timings = timings
+		return res
+	}
+
+	res := inspectCertificate(conn.ConnectionState().PeerCertificates, u.Hostname(), site.TLS, time.Now(), true)
+	res.Latency = time.Since(start)
+	res.Timings = timings
+	return res
+}
+
+// inspectCertificate reports on the leaf of a peer certificate chain and, when
+// verify is set, verifies the chain for host. The site is unhealthy when the
+// chain is invalid or expired, and gets a warning when the leaf expires within
+// the warning threshold.
+func inspectCertificate(certs []*x509.Certificate, host string, opts *sitestore.TLSOptions, now time.Time, verify bool) sitestore.CheckResult {
+	if len(certs) == 0 {
+		return failure(sitestore.TLSError, errors.New("No peer certificate"))
+	}
+	leaf := certs[0]
+
+	warningDays := sitestore.DefaultExpiryWarningDays
+	if opts != nil && opts.ExpiryWarningDays > 0 {
+		warningDays = opts.ExpiryWarningDays
+	}
+
+	cert := &sitestore.Certificate{
+		Subject:          leaf.Subject.String(),
+		Issuer:           leaf.Issuer.String(),
+		SANs:             leaf.DNSNames,
+		NotAfter:         leaf.NotAfter,
+		DaysUntilExpiry:  int(math.Floor(leaf.NotAfter.Sub(now).Hours() / 24)),
+		HostnameMismatch: leaf.VerifyHostname(host) != nil,
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	res := sitestore.CheckResult{Status: sitestore.Healthy, Certificate: cert}
+
+	var err error
+	if verify {
+		_, err = leaf.Verify(x509.VerifyOptions{
+			DNSName:       host,
+			Roots:         rootCAs,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+		})
+	}
+
+	if err != nil {
+		res.Status = sitestore.Unhealthy
+		res.ErrorClass = sitestore.TLSError
+		res.ErrorMsg = err.Error()
+	} else if cert.DaysUntilExpiry < warningDays {
+		res.Status = sitestore.Warning
+		res.ErrorClass = sitestore.TLSError
+		res.ErrorMsg = fmt.Sprintf("Certificate expires in %d days", cert.DaysUntilExpiry)
+	}
+
+	return res
+}
@@ -0,0 +1,196 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+// newTestCertificate issues a certificate for 127.0.0.1 signed by a fresh CA
+// and returns it along with a pool trusting that CA
+func newTestCertificate(t *testing.T, notAfter time.Time, ips ...net.IP) (tls.Certificate, *x509.CertPool) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Go Health Test CA"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "zempag.test"},
+		DNSNames:     []string{"zempag.test"},
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	return tls.Certificate{Certificate: [][]byte{der, caDER}, PrivateKey: key}, roots
+}
+
+// serveTLS completes TLS handshakes with cert on a local port
+func serveTLS(t *testing.T, cert tls.Certificate) string {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return "tls://" + ln.Addr().String()
+}
+
+func TestCheckTLS(t *testing.T) {
+	localhost := net.ParseIP("127.0.0.1")
+
+	var testCases = []struct {
+		name        string
+		notAfter    time.Time
+		ips         []net.IP
+		opts        *sitestore.TLSOptions
+		expStatus   int
+		expMismatch bool
+	}{
+		{
+			name:      "Inspecting a valid certificate",
+			notAfter:  time.Now().Add(90 * 24 * time.Hour),
+			ips:       []net.IP{localhost},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Inspecting a certificate that expires soon",
+			notAfter:  time.Now().Add(10 * 24 * time.Hour),
+			ips:       []net.IP{localhost},
+			expStatus: sitestore.Warning,
+		},
+		{
+			name:      "Inspecting a certificate within a custom threshold",
+			notAfter:  time.Now().Add(20 * 24 * time.Hour),
+			ips:       []net.IP{localhost},
+			opts:      &sitestore.TLSOptions{ExpiryWarningDays: 30},
+			expStatus: sitestore.Warning,
+		},
+		{
+			name:      "Inspecting an expired certificate",
+			notAfter:  time.Now().Add(-time.Hour),
+			ips:       []net.IP{localhost},
+			expStatus: sitestore.Unhealthy,
+		},
+		{
+			name:        "Inspecting a certificate for another host",
+			notAfter:    time.Now().Add(90 * 24 * time.Hour),
+			expStatus:   sitestore.Unhealthy,
+			expMismatch: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cert, roots := newTestCertificate(t, tc.notAfter, tc.ips...)
+
+			// Mocking
+			implementedRootCAs := rootCAs
+			defer func() {
+				rootCAs = implementedRootCAs
+			}()
+			rootCAs = roots
+
+			site := sitestore.Site{URL: serveTLS(t, cert), CheckType: sitestore.TLSCheck, TLS: tc.opts}
+			res := checkTLS(context.Background(), site, 800*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			c := res.Certificate
+			if c == nil {
+				t.Fatalf("Expected certificate to be reported but got nil")
+			}
+
+			if c.Issuer != "CN=Go Health Test CA" || c.SANs[0] != "zempag.test" {
+				t.Errorf("Expected certificate issuer and SANs to be reported but got %+v", c)
+			}
+
+			if c.HostnameMismatch != tc.expMismatch {
+				t.Errorf("Expected hostname mismatch to be %v but got %v", tc.expMismatch, c.HostnameMismatch)
+			}
+
+			if res.Timings == nil || res.Timings.TLSHandshake <= 0 {
+				t.Errorf("Expected TLS handshake duration to be recorded but got %+v", res.Timings)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP_Certificate(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// Mocking
+	implementedSiteChecker := siteChecker
+	defer func() {
+		siteChecker = implementedSiteChecker
+	}()
+
+	// Trust the test server certificate
+	siteChecker = func(req *http.Request, timeout time.Duration, _ bool) (*http.Response, error) {
+		client := ts.Client()
+		client.Timeout = timeout
+		return client.Do(req)
+	}
+
+	// The test server certificate is valid until 2084
+	site := sitestore.Site{URL: ts.URL, TLS: &sitestore.TLSOptions{ExpiryWarningDays: 365 * 100}}
+	res := checkHTTP(context.Background(), site, 800*time.Millisecond)
+
+	if res.Status != sitestore.Warning {
+		t.Errorf("Expected HTTPS site to get an expiry warning but got %+v", res)
+	}
+
+	if res.Certificate == nil || res.Certificate.DaysUntilExpiry <= 0 {
+		t.Errorf("Expected certificate to be reported but got %+v", res.Certificate)
+	}
+}
@@ -0,0 +1,126 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func init() {
+	Register(sitestore.WebSocketCheck, CheckerFunc(checkWebSocket))
+}
+
+func checkWebSocket(ctx context.Context, site sitestore.Site, timeout time.Duration) sitestore.CheckResult {
+	var opts sitestore.WebSocketOptions
+	if site.WebSocket != nil {
+		opts = *site.WebSocket
+	}
+
+	expect, err := expectedReply(opts)
+	if err != nil {
+		return failure(sitestore.UnknownError, err)
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: timeout,
+		TLSClientConfig:  &tls.Config{RootCAs: rootCAs},
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, resp, err := dialer.DialContext(dialCtx, site.URL, nil)
+	handshake := time.Since(start)
+	if err != nil {
+		var res sitestore.CheckResult
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			res = failure(sitestore.StatusMismatchError,
+				fmt.Errorf("Unexpected status code %d, expected %d", resp.StatusCode, http.StatusSwitchingProtocols))
+			res.StatusCode = resp.StatusCode
+		} else {
+			res = failure(classifyError(err), err)
+		}
+		res.Latency = handshake
+		res.Timings = &sitestore.Timings{Handshake: handshake}
+		return res
+	}
+	defer conn.Close()
+
+	// The exchange runs on deadlines, closing the connection is what stops
+	// it once the check is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	res := sitestore.CheckResult{
+		Status:     sitestore.Healthy,
+		StatusCode: resp.StatusCode,
+		Timings:    &sitestore.Timings{Handshake: handshake},
+	}
+
+	if opts.Send != "" {
+		conn.SetWriteDeadline(start.Add(timeout))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(opts.Send)); err != nil {
+			res = failure(classifyError(err), err)
+			res.Latency = time.Since(start)
+			res.Timings = &sitestore.Timings{Handshake: handshake}
+			return res
+		}
+	}
+
+	if expect != nil {
+		conn.SetReadDeadline(start.Add(timeout))
+		conn.SetReadLimit(maxTCPReplySize)
+		size, err := readMessageUntil(conn, expect)
+		res.BodySize = size
+		if err != nil {
+			res.Status = sitestore.Unhealthy
+			res.ErrorClass = classifyError(err)
+			if res.ErrorClass == sitestore.UnknownError {
+				res.ErrorClass = sitestore.AssertionError
+			}
+			res.ErrorMsg = fmt.Sprintf("Reply does not match %q: %v", expect, err)
+		}
+	}
+
+	res.Latency = time.Since(start)
+	return res
+}
+
+// expectedReply returns the regular expression replies must match, nil when
+// no reply is expected
+func expectedReply(opts sitestore.WebSocketOptions) (*regexp.Regexp, error) {
+	switch {
+	case opts.Expect != "":
+		return regexp.Compile(opts.Expect)
+	case opts.Send != "":
+		return regexp.MustCompile("^" + regexp.QuoteMeta(opts.Send) + "$"), nil
+	}
+
+	return nil, nil
+}
+
+// readMessageUntil reads messages from conn until one matches re, and returns
+// the number of bytes read. It gives up when the connection is closed or its
+// deadline passes.
+func readMessageUntil(conn *websocket.Conn, re *regexp.Regexp) (int64, error) {
+	var size int64
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return size, err
+		}
+
+		size += int64(len(msg))
+		if re.Match(msg) {
+			return size, nil
+		}
+	}
+}
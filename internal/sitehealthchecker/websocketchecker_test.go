@@ -0,0 +1,101 @@
+package sitehealthchecker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/levady/gohealth/internal/platform/sitestore"
+)
+
+func TestCheckWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"welcome"}`))
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if r.URL.Path == "/echo" {
+				conn.WriteMessage(mt, msg)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	var testCases = []struct {
+		name       string
+		url        string
+		opts       *sitestore.WebSocketOptions
+		expStatus  int
+		expErrType string
+	}{
+		{
+			name:      "Performing the handshake only",
+			url:       wsURL + "/echo",
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Sending a message that is echoed",
+			url:       wsURL + "/echo",
+			opts:      &sitestore.WebSocketOptions{Send: "ping"},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:      "Expecting a reply matching a regex",
+			url:       wsURL + "/silent",
+			opts:      &sitestore.WebSocketOptions{Expect: `"type":"welcome"`},
+			expStatus: sitestore.Healthy,
+		},
+		{
+			name:       "Sending a message that is not echoed",
+			url:        wsURL + "/silent",
+			opts:       &sitestore.WebSocketOptions{Send: "ping"},
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.TimeoutError,
+		},
+		{
+			name:       "Upgrading an endpoint without WebSocket",
+			url:        wsURL + "/plain",
+			expStatus:  sitestore.Unhealthy,
+			expErrType: sitestore.StatusMismatchError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site := sitestore.Site{URL: tc.url, CheckType: sitestore.WebSocketCheck, WebSocket: tc.opts}
+			res := checkWebSocket(context.Background(), site, 300*time.Millisecond)
+
+			if res.Status != tc.expStatus {
+				t.Errorf("Expected status %v but got %+v", tc.expStatus, res)
+			}
+
+			if res.ErrorClass != tc.expErrType {
+				t.Errorf("Expected error class %q but got %q (%s)", tc.expErrType, res.ErrorClass, res.ErrorMsg)
+			}
+
+			if res.Timings == nil || res.Timings.Handshake <= 0 {
+				t.Errorf("Expected handshake latency to be recorded but got %+v", res.Timings)
+			}
+		})
+	}
+}